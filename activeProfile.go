@@ -0,0 +1,140 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProfileSource identifies where the active profile name was resolved from.
+type ProfileSource string
+
+const (
+	ProfileSourceFlag      ProfileSource = "flag"
+	ProfileSourceEnv       ProfileSource = "env"
+	ProfileSourceDirectory ProfileSource = "directory"
+	ProfileSourceDefault   ProfileSource = "default"
+)
+
+// profilePinFilePermissions allows a pin file to be committed and shared within a project
+const profilePinFilePermissions = 0o644
+
+// ActiveProfile is the profile resolved by ResolveActiveProfile and the source that selected it.
+type ActiveProfile struct {
+	Name   string
+	Source ProfileSource
+	// Origin details the source, e.g. the environment variable name or the path of the pin file.
+	Origin string
+}
+
+// WithProfileFlag sets the profile selected on the command line. It takes precedence over all
+// other sources when resolving the active profile, except a default profile locked by the system
+// profiles. An empty name is ignored.
+func WithProfileFlag(profileName string) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.flagProfile = profileName
+		return c
+	}
+}
+
+// WithProfileEnvVar names an environment variable (e.g. "MYAPP_PROFILE") that selects the active
+// profile ahead of the stored default profile, similar to AWS_PROFILE.
+func WithProfileEnvVar(envVar string) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.profileEnvVar = envVar
+		return c
+	}
+}
+
+// WithProfilePinFile names a marker file (e.g. ".myapp-profile") holding a profile name. The nearest
+// such file found walking up from the working directory pins the active profile for that directory
+// tree, ahead of the stored default profile. See PinProfile.
+func WithProfilePinFile(fileName string) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.profilePinFile = fileName
+		return c
+	}
+}
+
+// PinProfile pins the specified profile for dir and its subdirectories by writing the pin file into dir
+func PinProfile(p *Profiler, dir, profileName string) error {
+	if p.config.profilePinFile == "" {
+		return p.newProfileError(OpPinProfile, profileName, ErrProfilePinFileNotSet)
+	}
+	if !p.profileExists(profileName) {
+		return p.newProfileError(OpPinProfile, profileName, ErrMissingProfileName)
+	}
+	path := filepath.Join(dir, p.config.profilePinFile)
+	return p.newProfileError(OpPinProfile, profileName, os.WriteFile(path, []byte(profileName+"\n"), profilePinFilePermissions))
+}
+
+// UnpinProfile removes the pin file from dir. It is not an error if dir has no pin file.
+func UnpinProfile(p *Profiler, dir string) error {
+	if p.config.profilePinFile == "" {
+		return p.newProfileError(OpPinProfile, "", ErrProfilePinFileNotSet)
+	}
+	err := os.Remove(filepath.Join(dir, p.config.profilePinFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return p.newProfileError(OpPinProfile, "", err)
+}
+
+// ResolveActiveProfile resolves the name of the active profile from, in order of precedence,
+// the profile flag, the profile environment variable, the nearest profile pin file and the
+// default profile. A default profile locked by the system profiles overrides all of them.
+func ResolveActiveProfile(p *Profiler) (ActiveProfile, error) {
+	if name := p.lockedDefaultProfile(); name != "" {
+		return ActiveProfile{Name: name, Source: ProfileSourceDefault, Origin: p.config.systemProfilesDir}, nil
+	}
+	if p.config.flagProfile != "" {
+		return ActiveProfile{Name: p.config.flagProfile, Source: ProfileSourceFlag}, nil
+	}
+	if p.config.profileEnvVar != "" {
+		if name := os.Getenv(p.config.profileEnvVar); name != "" {
+			return ActiveProfile{Name: name, Source: ProfileSourceEnv, Origin: p.config.profileEnvVar}, nil
+		}
+	}
+	if p.config.profilePinFile != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return ActiveProfile{}, p.newProfileError(OpUseProfile, "", err)
+		}
+		active, err := findPinnedProfile(wd, p.config.profilePinFile)
+		if err != nil {
+			return ActiveProfile{}, p.newProfileError(OpUseProfile, active.Name, err)
+		}
+		if active.Name != "" {
+			return active, nil
+		}
+	}
+	if name := p.defaultProfile(); name != "" {
+		return ActiveProfile{Name: name, Source: ProfileSourceDefault}, nil
+	}
+	return ActiveProfile{}, p.newProfileError(OpUseProfile, "", ErrMissingDefaultProfile)
+}
+
+// findPinnedProfile walks up from dir to the file system root looking for the pin file
+func findPinnedProfile(dir, fileName string) (ActiveProfile, error) {
+	for {
+		path := filepath.Join(dir, fileName)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			name := strings.TrimSpace(string(data))
+			if err := validateProfileName(name); err != nil {
+				return ActiveProfile{Name: name}, fmt.Errorf("invalid profile pinned in %s: %w", path, err)
+			}
+			return ActiveProfile{Name: name, Source: ProfileSourceDirectory, Origin: path}, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return ActiveProfile{}, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ActiveProfile{}, nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,408 @@
+package profiles
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+const (
+	bundleVersion = "1"
+	bundleSaltLen = 16
+)
+
+// ConflictPolicy decides how Import handles a profile whose name already exists.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing profile (default).
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing profile.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename imports the profile under a new, unused name.
+	ConflictRename ConflictPolicy = "rename"
+)
+
+// ImportAction is the action taken (or, in a dry run, planned) for a profile by Import.
+type ImportAction string
+
+const (
+	ImportAdded       ImportAction = "added"
+	ImportOverwritten ImportAction = "overwritten"
+	ImportRenamed     ImportAction = "renamed"
+	ImportSkipped     ImportAction = "skipped"
+)
+
+// ExportOptions configure Export.
+type ExportOptions struct {
+	// Profiles to export. All user profiles are exported when empty.
+	Profiles []string
+	// Passphrase, when set, seals the bundle with a key derived from it.
+	Passphrase string
+}
+
+// ImportOptions configure Import.
+type ImportOptions struct {
+	// Passphrase of a sealed bundle.
+	Passphrase string
+	// OnConflict decides how existing profile names are handled. Defaults to ConflictSkip.
+	OnConflict ConflictPolicy
+	// DryRun reports the planned actions without changing any profile.
+	DryRun bool
+	// SetDefault sets the bundle's default profile as the default profile when it is imported.
+	SetDefault bool
+}
+
+// ImportResult reports the outcome of importing a single profile.
+type ImportResult struct {
+	// Name of the profile in the bundle.
+	Name string
+	// ImportedAs is the name the profile was stored under, which differs from Name when renamed.
+	ImportedAs string
+	Action     ImportAction
+	// Err is the reason a profile was skipped, when it could not be imported.
+	Err error
+}
+
+// bundle is the portable archive of profiles written by Export
+type bundle struct {
+	Version        string          `json:"version"`
+	Namespace      string          `json:"namespace,omitempty"`
+	DefaultProfile string          `json:"defaultProfile,omitempty"`
+	Profiles       []bundleProfile `json:"profiles,omitempty"`
+	Sealed         *sealedBundle   `json:"sealed,omitempty"`
+}
+
+type bundleProfile struct {
+	Name string          `json:"name"`
+	Info ProfileInfo     `json:"info"`
+	Data json.RawMessage `json:"data"`
+}
+
+// sealedBundle holds a bundle encrypted with a passphrase-derived key
+type sealedBundle struct {
+	KeyDerivation string `json:"keyDerivation"`
+	Salt          []byte `json:"salt"`
+	Cipher        string `json:"cipher"`
+	Data          []byte `json:"data"`
+}
+
+// Export writes the global configuration and the selected profiles, including their secrets and
+// metadata, to w as a single portable JSON bundle, optionally sealed with a passphrase.
+func Export(p *Profiler, w io.Writer, opts ExportOptions) error {
+	names := opts.Profiles
+	if len(names) == 0 {
+		names = p.globalStore.ListProfiles()
+	}
+
+	newStore := newStoreFactory(p.config)
+	b := bundle{
+		Version:   bundleVersion,
+		Namespace: p.config.configName,
+	}
+	if defaultProfile := p.globalStore.GetDefaultProfile(); slices.Contains(names, defaultProfile) {
+		b.DefaultProfile = defaultProfile
+	}
+	for _, name := range names {
+		if !p.globalStore.ProfileExists(name) {
+			return p.newProfileError(OpExportProfiles, name, ErrMissingProfileName)
+		}
+		s, err := newStore(p.config.configName, getStoreKey(name))
+		if err != nil {
+			return p.newProfileError(OpExportProfiles, name, err)
+		}
+		data, err := s.Get()
+		if err != nil {
+			return p.newProfileError(OpExportProfiles, name, mapStoreError(err))
+		}
+		b.Profiles = append(b.Profiles, bundleProfile{Name: name, Info: p.globalStore.GetProfileInfo(name), Data: data})
+	}
+
+	if opts.Passphrase != "" {
+		sealed, err := sealBundle(b, opts.Passphrase)
+		if err != nil {
+			return p.newProfileError(OpExportProfiles, "", err)
+		}
+		b = bundle{Version: bundleVersion, Sealed: sealed}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return p.newProfileError(OpExportProfiles, "", enc.Encode(b))
+}
+
+// Import reads a bundle written by Export and adds its profiles and metadata. Each profile is
+// decoded as T so struct tags such as secret fields apply when it is stored. Existing names are
+// handled by opts.OnConflict, and with opts.DryRun the planned actions are returned without changes.
+func Import[T NamedProfile](p *Profiler, r io.Reader, opts ImportOptions) ([]ImportResult, error) {
+	var b bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, p.newProfileError(OpImportProfiles, "", fmt.Errorf("%w: %w", ErrInvalidBundle, err))
+	}
+	if b.Sealed != nil {
+		var err error
+		if b, err = openBundle(b.Sealed, opts.Passphrase); err != nil {
+			return nil, p.newProfileError(OpImportProfiles, "", err)
+		}
+	}
+	if b.Version != bundleVersion {
+		return nil, p.newProfileError(OpImportProfiles, "", fmt.Errorf("%w: unsupported version %q", ErrInvalidBundle, b.Version))
+	}
+
+	// Plan all actions first so a dry run and a real import agree
+	results := make([]ImportResult, 0, len(b.Profiles))
+	renamed := make(map[string]string, len(b.Profiles))
+	taken := make(map[string]bool)
+	for _, bp := range b.Profiles {
+		if err := validateProfileName(bp.Name); err != nil {
+			return nil, p.newProfileError(OpImportProfiles, bp.Name, err)
+		}
+		result := ImportResult{Name: bp.Name, ImportedAs: bp.Name, Action: ImportAdded}
+		if p.profileNameTaken(bp.Name) || taken[bp.Name] {
+			switch opts.OnConflict {
+			case ConflictOverwrite:
+				result.Action = ImportOverwritten
+			case ConflictRename:
+				result.Action = ImportRenamed
+				result.ImportedAs = p.unusedProfileName(bp.Name, taken)
+			default:
+				result.Action = ImportSkipped
+			}
+		}
+		taken[result.ImportedAs] = true
+		renamed[bp.Name] = result.ImportedAs
+		results = append(results, result)
+	}
+	if opts.DryRun {
+		return results, nil
+	}
+
+	for i := range b.Profiles {
+		result := results[i]
+		if result.Action == ImportSkipped {
+			continue
+		}
+		if err := importProfile[T](p, b, results, i, renamed); err != nil {
+			return results[:i], p.newProfileError(OpImportProfiles, result.ImportedAs, err)
+		}
+	}
+
+	if opts.SetDefault && b.DefaultProfile != "" {
+		if i := slices.IndexFunc(results, func(r ImportResult) bool { return r.Name == b.DefaultProfile }); i >= 0 && results[i].Action != ImportSkipped {
+			if err := SetDefaultProfile(p, results[i].ImportedAs); err != nil {
+				return results, err
+			}
+		}
+	}
+	return results, nil
+}
+
+// importProfile stores the i-th profile of a bundle and its metadata under its ImportedAs name,
+// checked like a saved profile
+func importProfile[T NamedProfile](p *Profiler, b bundle, results []ImportResult, i int, renamed map[string]string) error {
+	bp, result := b.Profiles[i], results[i]
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bp.Data, &fields); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	if result.Action == ImportRenamed {
+		var err error
+		if fields, err = renameProfileFields(fields, bp.Name, result.ImportedAs, reflect.TypeFor[T]()); err != nil {
+			return err
+		}
+	}
+	inherited, err := p.bundleAncestorData(b, results, i)
+	if err != nil {
+		return err
+	}
+	value, err := prepareStoredFields[T](p, fields, inherited)
+	if err != nil {
+		return err
+	}
+
+	// An overwritten profile in the trash is purged first
+	for _, deleted := range p.globalStore.ListTrash() {
+		if deleted.Name == result.ImportedAs {
+			if err := p.purgeDeletedProfile(deleted); err != nil {
+				return err
+			}
+		}
+	}
+
+	s, err := newStoreFactory(p.config)(p.config.configName, getStoreKey(result.ImportedAs))
+	if err != nil {
+		return err
+	}
+	if result.Action == ImportOverwritten {
+		if err := p.recordRevision(result.ImportedAs, s, reflect.TypeFor[T]()); err != nil {
+			return err
+		}
+	}
+	if err := s.Set(value); err != nil {
+		return mapStoreError(err)
+	}
+	if !p.globalStore.ProfileExists(result.ImportedAs) {
+		if err := p.globalStore.AddProfile(result.ImportedAs); err != nil {
+			return err
+		}
+	}
+	if p.currentProfileStore != nil && p.currentProfileStore.GetProfileName() == result.ImportedAs {
+		p.currentProfileStore = nil
+	}
+
+	return p.globalStore.UpdateProfileInfo(result.ImportedAs, func(info *ProfileInfo) {
+		if !bp.Info.CreatedAt.IsZero() {
+			info.CreatedAt = bp.Info.CreatedAt
+		}
+		info.Description = bp.Info.Description
+		info.Tags = bp.Info.Tags
+		info.Parent = ""
+		if parent, ok := renamed[bp.Info.Parent]; ok {
+			info.Parent = parent
+		} else if p.globalStore.ProfileExists(bp.Info.Parent) {
+			info.Parent = bp.Info.Parent
+		}
+	})
+}
+
+// bundleAncestorData returns the data of the profiles the i-th bundled profile will inherit from,
+// root first, taken from the bundle for the imported ones and from the store otherwise. Profiles
+// are compared by the name they are imported as, so inheriting from a stored profile that already
+// inherits from an imported one is reported as a cycle.
+func (p *Profiler) bundleAncestorData(b bundle, results []ImportResult, i int) ([][]byte, error) {
+	var data [][]byte
+	seen := map[string]bool{results[i].ImportedAs: true}
+	for parent := b.Profiles[i].Info.Parent; parent != ""; {
+		if j := slices.IndexFunc(b.Profiles, func(bp bundleProfile) bool { return bp.Name == parent }); j >= 0 && results[j].Action != ImportSkipped {
+			if seen[results[j].ImportedAs] {
+				return nil, fmt.Errorf("%w: %q", ErrProfileInheritanceCycle, parent)
+			}
+			seen[results[j].ImportedAs] = true
+			data = append([][]byte{b.Profiles[j].Data}, data...)
+			parent = b.Profiles[j].Info.Parent
+			continue
+		}
+		if !p.globalStore.ProfileExists(parent) {
+			// the inheritance is dropped on import
+			break
+		}
+		ancestors, err := p.profileAncestors(parent)
+		if err != nil {
+			return nil, err
+		}
+		for _, ancestor := range append(ancestors, parent) {
+			if seen[ancestor] {
+				return nil, fmt.Errorf("%w: %q inherits from %q", ErrProfileInheritanceCycle, parent, ancestor)
+			}
+		}
+		stored, err := p.storedAncestorData(parent)
+		if err != nil {
+			return nil, err
+		}
+		s, err := newStoreFactory(p.config)(p.config.configName, getStoreKey(parent))
+		if err != nil {
+			return nil, err
+		}
+		parentData, err := s.Get()
+		if err != nil {
+			return nil, mapStoreError(err)
+		}
+		data = append(append(stored, parentData), data...)
+		break
+	}
+	return data, nil
+}
+
+// profileNameTaken returns true if a profile or a profile in the trash has the name
+func (p *Profiler) profileNameTaken(profileName string) bool {
+	return p.globalStore.ProfileExists(profileName) || p.globalStore.InTrash(profileName)
+}
+
+// unusedProfileName returns the first of name-imported, name-imported-2, ... that is not in use
+func (p *Profiler) unusedProfileName(name string, taken map[string]bool) string {
+	candidate := name + "-imported"
+	for i := 2; p.profileNameTaken(candidate) || taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-imported-%d", name, i)
+	}
+	return candidate
+}
+
+// renameProfileFields changes the name held by the profile data by finding the top-level
+// field that makes GetName return the new name when decoded as profileType
+func renameProfileFields(fields map[string]json.RawMessage, oldName, newName string, profileType reflect.Type) (map[string]json.RawMessage, error) {
+	oldValue, err := json.Marshal(oldName)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := json.Marshal(newName)
+	if err != nil {
+		return nil, err
+	}
+	for key, v := range fields {
+		if !jsonEqual(v, oldValue) {
+			continue
+		}
+		renamed := make(map[string]json.RawMessage, len(fields))
+		for k, v := range fields {
+			renamed[k] = v
+		}
+		renamed[key] = newValue
+
+		data, err := json.Marshal(renamed)
+		if err != nil {
+			return nil, err
+		}
+		profile := reflect.New(profileType)
+		if err := json.Unmarshal(data, profile.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		}
+		if named, ok := profile.Elem().Interface().(NamedProfile); ok && named.GetName() == newName {
+			return renamed, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: cannot rename %q to %q", ErrInvalidBundle, oldName, newName)
+}
+
+func sealBundle(b bundle, passphrase string) (*sealedBundle, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	sealed := &sealedBundle{
+		KeyDerivation: "argon2id",
+		Salt:          make([]byte, bundleSaltLen),
+		Cipher:        store.CipherXChaCha20Poly1305.Name(),
+	}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return nil, err
+	}
+	key := store.DeriveKey(passphrase, sealed.Salt, store.CipherXChaCha20Poly1305.KeySize())
+	if sealed.Data, err = store.CipherXChaCha20Poly1305.Encrypt(key, data); err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
+
+func openBundle(sealed *sealedBundle, passphrase string) (bundle, error) {
+	var b bundle
+	if passphrase == "" {
+		return b, store.ErrPassphraseRequired
+	}
+	if sealed.KeyDerivation != "argon2id" || sealed.Cipher != store.CipherXChaCha20Poly1305.Name() {
+		return b, fmt.Errorf("%w: unsupported sealing %s/%s", ErrInvalidBundle, sealed.KeyDerivation, sealed.Cipher)
+	}
+	key := store.DeriveKey(passphrase, sealed.Salt, store.CipherXChaCha20Poly1305.KeySize())
+	data, err := store.CipherXChaCha20Poly1305.Decrypt(key, sealed.Data)
+	if err != nil {
+		return b, fmt.Errorf("%w: %w", ErrProfileDecryption, err)
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	return b, nil
+}
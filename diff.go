@@ -0,0 +1,185 @@
+package profiles
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// CurrentRevision selects the stored value of a profile, rather than one of its revisions,
+// in DiffProfileRevisions.
+const CurrentRevision = 0
+
+// DiffKind is the kind of difference of a field between two profiles.
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// FieldDiff is a difference of a single field between two profiles. Values of fields tagged
+// `osprofiles:"secret"` are replaced by DefaultRedactPlaceholder.
+type FieldDiff struct {
+	// Path is the dot-separated JSON path of the field, e.g. "auth.user" or "scopes.0".
+	Path string   `json:"path"`
+	Kind DiffKind `json:"kind"`
+	// Old is the value in the first profile, nil when added.
+	Old any `json:"old,omitempty"`
+	// New is the value in the second profile, nil when removed.
+	New any `json:"new,omitempty"`
+}
+
+// DiffProfiles returns the field-level differences from profile a to profile b, sorted by path
+func DiffProfiles[T NamedProfile](p *Profiler, a, b string) ([]FieldDiff, error) {
+	profileA, err := typedProfile[T](GetProfile[T](p, a))
+	if err != nil {
+		return nil, err
+	}
+	profileB, err := typedProfile[T](GetProfile[T](p, b))
+	if err != nil {
+		return nil, err
+	}
+
+	dataA, err := json.Marshal(profileA)
+	if err != nil {
+		return nil, err
+	}
+	dataB, err := json.Marshal(profileB)
+	if err != nil {
+		return nil, err
+	}
+	return diffProfileData(dataA, dataB, reflect.TypeFor[T]())
+}
+
+// DiffProfileRevisions returns the field-level differences of the specified profile from revision
+// from to revision to, sorted by path. CurrentRevision selects the stored profile. The stored values
+// are compared, so a profile with a parent only shows the fields it overrides.
+func DiffProfileRevisions[T NamedProfile](p *Profiler, profileName string, from, to int) ([]FieldDiff, error) {
+	if !p.globalStore.ProfileExists(profileName) {
+		return nil, p.newProfileError(OpGetProfile, profileName, ErrMissingProfileName)
+	}
+	dataFrom, err := p.storedRevisionData(profileName, from)
+	if err != nil {
+		return nil, p.newProfileError(OpGetProfile, profileName, err)
+	}
+	dataTo, err := p.storedRevisionData(profileName, to)
+	if err != nil {
+		return nil, p.newProfileError(OpGetProfile, profileName, err)
+	}
+	return diffProfileData(dataFrom, dataTo, reflect.TypeFor[T]())
+}
+
+// storedRevisionData returns the stored value of a revision of a profile, or of the profile itself
+func (p *Profiler) storedRevisionData(profileName string, revision int) ([]byte, error) {
+	if revision != CurrentRevision {
+		return p.revisionData(profileName, revision)
+	}
+	s, err := newStoreFactory(p.config)(p.config.configName, getStoreKey(profileName))
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.Get()
+	return data, mapStoreError(err)
+}
+
+func diffProfileData(a, b []byte, profileType reflect.Type) ([]FieldDiff, error) {
+	valueA, err := decodeJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+	valueB, err := decodeJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []FieldDiff
+	diffValues(valueA, valueB, profileType, "", false, &diffs)
+	slices.SortFunc(diffs, func(x, y FieldDiff) int {
+		return cmp.Compare(x.Path, y.Path)
+	})
+	return diffs, nil
+}
+
+// decodeJSONValue decodes JSON keeping numbers exact
+func decodeJSONValue(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+	}
+	return v, nil
+}
+
+// diffValues appends the differences between decoded JSON values a and b of Go type t at path
+func diffValues(a, b any, t reflect.Type, path string, secret bool, diffs *[]FieldDiff) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	mapA, okA := a.(map[string]any)
+	mapB, okB := b.(map[string]any)
+	if okA && okB && !secret {
+		keys := make(map[string]bool, len(mapA)+len(mapB))
+		for k := range mapA {
+			keys[k] = true
+		}
+		for k := range mapB {
+			keys[k] = true
+		}
+		for k := range keys {
+			childType, childSecret := jsonFieldType(t, k)
+			childA, inA := mapA[k]
+			childB, inB := mapB[k]
+			childPath := joinJSONPath(path, k)
+			switch {
+			case !inA:
+				*diffs = append(*diffs, FieldDiff{Path: childPath, Kind: DiffAdded, New: maskSecret(childB, childSecret)})
+			case !inB:
+				*diffs = append(*diffs, FieldDiff{Path: childPath, Kind: DiffRemoved, Old: maskSecret(childA, childSecret)})
+			default:
+				diffValues(childA, childB, childType, childPath, childSecret, diffs)
+			}
+		}
+		return
+	}
+
+	sliceA, okA := a.([]any)
+	sliceB, okB := b.([]any)
+	if okA && okB && !secret {
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+		for i := 0; i < max(len(sliceA), len(sliceB)); i++ {
+			childPath := joinJSONPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(sliceA):
+				*diffs = append(*diffs, FieldDiff{Path: childPath, Kind: DiffAdded, New: sliceB[i]})
+			case i >= len(sliceB):
+				*diffs = append(*diffs, FieldDiff{Path: childPath, Kind: DiffRemoved, Old: sliceA[i]})
+			default:
+				diffValues(sliceA[i], sliceB[i], elemType, childPath, false, diffs)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, FieldDiff{Path: path, Kind: DiffChanged, Old: maskSecret(a, secret), New: maskSecret(b, secret)})
+	}
+}
+
+// maskSecret replaces the value of a secret field with a placeholder
+func maskSecret(v any, secret bool) any {
+	if secret && v != nil {
+		return DefaultRedactPlaceholder
+	}
+	return v
+}
@@ -0,0 +1,151 @@
+package profiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+// EnvTagName is the struct tag naming the environment variable that overrides a profile field,
+// e.g. `env:"MYAPP_ENDPOINT"`.
+const EnvTagName = "env"
+
+// FieldOverride describes a profile field whose stored value was overridden by an environment variable.
+type FieldOverride struct {
+	// Field is the JSON name of the overridden field.
+	Field string
+	// EnvVar is the environment variable that provided the value.
+	EnvVar string
+	// Value is the raw value of the environment variable.
+	Value string
+
+	stored    json.RawMessage
+	effective json.RawMessage
+}
+
+// WithEnvOverrides applies environment variables named by `env` struct tags on the top-level fields
+// of loaded profiles. The loaded profile is the effective view, while Save keeps persisting the
+// stored value of an overridden field unless it was changed after loading.
+func WithEnvOverrides() profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.envOverrides = true
+		return c
+	}
+}
+
+// EnvOverrides returns the fields of the loaded profile overridden by environment variables
+func (p *ProfileStore) EnvOverrides() []FieldOverride {
+	return append([]FieldOverride(nil), p.overrides...)
+}
+
+// applyEnvOverrides sets the fields of the profile pointed to by target from their environment
+// variables, recording the stored value of each overridden field
+func applyEnvOverrides(target any, stored []byte) ([]FieldOverride, error) {
+	v := reflect.ValueOf(target).Elem()
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	var storedFields map[string]json.RawMessage
+	if err := json.Unmarshal(stored, &storedFields); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+	}
+
+	var overrides []FieldOverride
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		envVar := field.Tag.Get(EnvTagName)
+		if envVar == "" || !field.IsExported() {
+			continue
+		}
+		value, ok := os.LookupEnv(envVar)
+		if !ok || value == "" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		if fieldValue.Kind() == reflect.String {
+			fieldValue.SetString(value)
+		} else if err := json.Unmarshal([]byte(value), fieldValue.Addr().Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidEnvOverride, envVar, err)
+		}
+		effective, err := json.Marshal(fieldValue.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		name := store.JSONFieldName(field)
+		overrides = append(overrides, FieldOverride{
+			Field:     name,
+			EnvVar:    envVar,
+			Value:     value,
+			stored:    storedFields[name],
+			effective: effective,
+		})
+	}
+	return overrides, nil
+}
+
+// storedProfile returns the profile to persist, with overridden fields that still hold their
+// environment value restored to their stored value
+func (p *ProfileStore) storedProfile() (NamedProfile, error) {
+	if len(p.overrides) == 0 || p.Profile == nil {
+		return p.Profile, nil
+	}
+
+	data, err := json.Marshal(p.Profile)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, o := range p.overrides {
+		if current, ok := fields[o.Field]; !ok || !jsonEqual(current, o.effective) {
+			continue
+		}
+		if o.stored == nil {
+			delete(fields, o.Field)
+		} else {
+			fields[o.Field] = o.stored
+		}
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+
+	// Decode into the profile's own type so struct tags (e.g. secret fields) still apply
+	t := reflect.TypeOf(p.Profile)
+	if t.Kind() == reflect.Pointer {
+		restored := reflect.New(t.Elem())
+		if err := json.Unmarshal(data, restored.Interface()); err != nil {
+			return nil, err
+		}
+		return restored.Interface().(NamedProfile), nil
+	}
+	restored := reflect.New(t)
+	if err := json.Unmarshal(data, restored.Interface()); err != nil {
+		return nil, err
+	}
+	return restored.Elem().Interface().(NamedProfile), nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
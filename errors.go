@@ -1,6 +1,11 @@
 package profiles
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
 
 var (
 	ErrProfileNameConflict        = errors.New("error: profile name already exists in storage")
@@ -8,6 +13,109 @@ var (
 	ErrMissingDefaultProfile      = errors.New("error: default profile not set")
 	ErrMissingProfileName         = errors.New("error: profile name not found")
 	ErrInvalidStoreDriver         = errors.New("error: invalid store driver")
+	ErrNoAvailableStore           = errors.New("error: no available store driver")
 	ErrDeletingProfile            = errors.New("error: deleting profile with name")
 	ErrCannotDeleteDefaultProfile = errors.New("error: cannot delete default profile")
+	ErrProfilePermissionDenied    = errors.New("error: permission denied accessing profile")
+	ErrProfileCorrupt             = errors.New("error: stored profile is corrupt")
+	ErrProfileDecryption          = errors.New("error: stored profile could not be decrypted")
+	ErrProfileTypeMismatch        = errors.New("error: profile type mismatch")
+	ErrProfilePinFileNotSet       = errors.New("error: profile pin file name not configured")
+	ErrInvalidEnvOverride         = errors.New("error: invalid environment variable override")
+	ErrProfileInheritanceCycle    = errors.New("error: profile inheritance cycle")
+	ErrProfileHasChildren         = errors.New("error: cannot delete profile inherited by other profiles")
+	ErrProfileReadOnly            = errors.New("error: profile is read-only")
+	ErrDefaultProfileLocked       = errors.New("error: default profile is locked by the system configuration")
+	ErrInvalidBundle              = errors.New("error: invalid profile bundle")
+	ErrUnsupportedFormat          = errors.New("error: unsupported format")
+	ErrProfileRevisionNotFound    = errors.New("error: profile revision not found")
+	ErrOperationVetoed            = errors.New("error: operation vetoed by hook")
+	ErrProfileInvalid             = errors.New("error: profile is invalid")
+	ErrProfileSchemaViolation     = errors.New("error: profile does not match its schema")
 )
+
+// Operations reported by ProfileError
+const (
+	OpAddProfile        = "add"
+	OpGetProfile        = "get"
+	OpUseProfile        = "use"
+	OpUpdateProfile     = "update"
+	OpSetDefaultProfile = "set-default"
+	OpPinProfile        = "pin"
+	OpDeleteProfile     = "delete"
+	OpDeleteAllProfiles = "delete-all"
+	OpCleanup           = "cleanup"
+	OpRestoreProfile    = "restore"
+	OpPurgeTrash        = "purge-trash"
+	OpExportProfiles    = "export"
+	OpImportProfiles    = "import"
+)
+
+// ProfileError records a failed profile operation along with the profile, namespace and storage
+// driver involved. It unwraps to the underlying error, so errors.Is matches the sentinel errors
+// above, and the fields are available through errors.As.
+type ProfileError struct {
+	// Op is the operation that failed (e.g. OpDeleteProfile).
+	Op string
+	// Profile is the name of the profile, empty for operations on all profiles.
+	Profile string
+	// Namespace is the configName the Profiler was created with.
+	Namespace string
+	// Driver is the name of the storage driver in use.
+	Driver string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ProfileError) Error() string {
+	if e.Profile == "" {
+		return fmt.Sprintf("%s profiles (namespace %q, driver %q): %v", e.Op, e.Namespace, e.Driver, e.Err)
+	}
+	return fmt.Sprintf("%s profile %q (namespace %q, driver %q): %v", e.Op, e.Profile, e.Namespace, e.Driver, e.Err)
+}
+
+func (e *ProfileError) Unwrap() error {
+	return e.Err
+}
+
+// newProfileError wraps err in a ProfileError for the profiler, replacing the operation
+// of an existing ProfileError so the outermost operation is reported
+func (p *Profiler) newProfileError(op, profileName string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if pe, ok := err.(*ProfileError); ok {
+		if profileName == "" {
+			profileName = pe.Profile
+		}
+		err = pe.Err
+	}
+	return &ProfileError{
+		Op:        op,
+		Profile:   profileName,
+		Namespace: p.config.configName,
+		Driver:    string(p.config.driver),
+		Err:       err,
+	}
+}
+
+// mapStoreError wraps driver errors with the matching profile-level error. A missing entry is
+// reported as ErrMissingProfileName. The original error remains available to errors.Is.
+func mapStoreError(err error) error {
+	var profileErr error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, store.ErrNotFound):
+		profileErr = ErrMissingProfileName
+	case errors.Is(err, store.ErrPermissionDenied):
+		profileErr = ErrProfilePermissionDenied
+	case errors.Is(err, store.ErrDecryptionFailed):
+		profileErr = ErrProfileDecryption
+	case errors.Is(err, store.ErrStoredValueInvalid):
+		profileErr = ErrProfileCorrupt
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", profileErr, err)
+}
@@ -0,0 +1,170 @@
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jrschumacher/go-osprofiles/internal/global"
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+// ProfileRevision is a previous version of a profile kept in its history.
+type ProfileRevision = global.ProfileRevision
+
+// HistoryRetention limits the previous revisions kept of each profile.
+type HistoryRetention struct {
+	// MaxRevisions is the number of revisions kept per profile. History is disabled when less than 1.
+	MaxRevisions int
+	// MaxAge, when set, drops revisions older than it.
+	MaxAge time.Duration
+}
+
+// WithProfileHistory keeps previous revisions of a profile each time it is updated or restored,
+// stored by the same driver and encrypted the same way as the profile itself.
+func WithProfileHistory(retention HistoryRetention) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.history = retention
+		return c
+	}
+}
+
+// ProfileHistory returns the stored revisions of the specified profile, oldest first
+func ProfileHistory(p *Profiler, profileName string) ([]ProfileRevision, error) {
+	info, err := GetProfileInfo(p, profileName)
+	if err != nil {
+		return nil, err
+	}
+	return info.History, nil
+}
+
+// RestoreProfile replaces the specified profile with one of its revisions. The replaced version
+// is kept as a new revision, so a restore can itself be undone.
+func RestoreProfile[T NamedProfile](p *Profiler, profileName string, revision int) error {
+	if IsSystemProfile(p, profileName) {
+		return p.newProfileError(OpRestoreProfile, profileName, ErrProfileReadOnly)
+	}
+	if !p.globalStore.ProfileExists(profileName) {
+		return p.newProfileError(OpRestoreProfile, profileName, ErrMissingProfileName)
+	}
+	data, err := p.revisionData(profileName, revision)
+	if err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, fmt.Errorf("%w: %w", ErrProfileCorrupt, err))
+	}
+	inherited, err := p.storedAncestorData(profileName)
+	if err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, err)
+	}
+	value, err := prepareStoredFields[T](p, fields, inherited)
+	if err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, err)
+	}
+
+	live, err := newStoreFactory(p.config)(p.config.configName, getStoreKey(profileName))
+	if err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, err)
+	}
+	if err := p.recordRevision(profileName, live, reflect.TypeFor[T]()); err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, err)
+	}
+	if err := live.Set(value); err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, mapStoreError(err))
+	}
+
+	if p.currentProfileStore != nil && p.currentProfileStore.GetProfileName() == profileName {
+		p.currentProfileStore = nil
+	}
+	return p.newProfileError(OpRestoreProfile, profileName, p.touchProfileInfo(profileName, true))
+}
+
+// recordRevision keeps the value held by the live store of a profile as its next revision and
+// drops the revisions beyond the retention policy. It is a no-op when history is disabled.
+func (p *Profiler) recordRevision(profileName string, live store.StoreInterface, profileType reflect.Type) error {
+	if p.config.history.MaxRevisions < 1 || !live.Exists() {
+		return nil
+	}
+	data, err := live.Get()
+	if err != nil {
+		return mapStoreError(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+	}
+
+	history := p.globalStore.GetProfileInfo(profileName).History
+	next := ProfileRevision{Revision: 1, SavedAt: time.Now().UTC()}
+	if len(history) > 0 {
+		next.Revision = history[len(history)-1].Revision + 1
+	}
+	s, err := p.revisionStore(profileName, next.Revision)
+	if err != nil {
+		return err
+	}
+	if err := s.Set(store.PartialValue{Fields: fields, Type: profileType}); err != nil {
+		return mapStoreError(err)
+	}
+	history = append(history, next)
+
+	// Apply the retention policy
+	var expired []ProfileRevision
+	if excess := len(history) - p.config.history.MaxRevisions; excess > 0 {
+		expired, history = history[:excess], history[excess:]
+	}
+	if p.config.history.MaxAge > 0 {
+		cutoff := next.SavedAt.Add(-p.config.history.MaxAge)
+		i := slices.IndexFunc(history, func(r ProfileRevision) bool { return !r.SavedAt.Before(cutoff) })
+		expired, history = append(expired, history[:i]...), history[i:]
+	}
+	if err := p.globalStore.UpdateProfileInfo(profileName, func(info *ProfileInfo) {
+		info.History = history
+	}); err != nil {
+		return err
+	}
+	return p.deleteRevisions(profileName, expired)
+}
+
+// revisionData returns the stored value of a revision of a profile
+func (p *Profiler) revisionData(profileName string, revision int) ([]byte, error) {
+	history := p.globalStore.GetProfileInfo(profileName).History
+	if !slices.ContainsFunc(history, func(r ProfileRevision) bool { return r.Revision == revision }) {
+		return nil, fmt.Errorf("%w: %d", ErrProfileRevisionNotFound, revision)
+	}
+	s, err := p.revisionStore(profileName, revision)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.Get()
+	return data, mapStoreError(err)
+}
+
+// deleteRevisions removes the stored values of revisions of a profile
+func (p *Profiler) deleteRevisions(profileName string, revisions []ProfileRevision) error {
+	for _, r := range revisions {
+		s, err := p.revisionStore(profileName, r.Revision)
+		if err != nil {
+			return err
+		}
+		if s.Exists() {
+			if err := s.Delete(); err != nil {
+				return mapStoreError(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Profiler) revisionStore(profileName string, revision int) (store.StoreInterface, error) {
+	return newStoreFactory(p.config)(p.config.configName, getRevisionStoreKey(profileName, revision))
+}
+
+func getRevisionStoreKey(profileName string, revision int) string {
+	return global.STORE_KEY_REVISION + "-" + profileName + "-" + strconv.Itoa(revision)
+}
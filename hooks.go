@@ -0,0 +1,190 @@
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+// HookEvent describes a profile operation passed to lifecycle hooks.
+type HookEvent struct {
+	// Op is the operation: OpAddProfile, OpUpdateProfile, OpSetDefaultProfile, OpDeleteProfile or OpUseProfile.
+	Op string
+	// Profile is the name of the profile the operation applies to.
+	Profile string
+	// Value is the profile being added or updated, or the profile used once it is loaded. It is
+	// nil for the other operations.
+	Value NamedProfile
+}
+
+// Hooks are lifecycle callbacks run around profile operations.
+type Hooks struct {
+	// Ops limits the hooks to the listed operations. The hooks run for all operations when empty.
+	Ops []string
+	// Before runs before the operation. Returning an error vetoes the operation, which then
+	// fails with ErrOperationVetoed.
+	Before func(HookEvent) error
+	// After runs once the operation succeeded. A returned error is reported to the caller, but
+	// the operation is not undone.
+	After func(HookEvent) error
+}
+
+// Validator is implemented by profile types that check their own values. Validate is called
+// before a profile is saved, and a failure is reported as ErrProfileInvalid.
+type Validator interface {
+	Validate() error
+}
+
+// BeforeSaver is implemented by profile types that prepare their values before being saved.
+type BeforeSaver interface {
+	BeforeSave()
+}
+
+// AfterLoader is implemented by profile types that finish setting up their values after being loaded.
+type AfterLoader interface {
+	AfterLoad()
+}
+
+// WithHooks registers lifecycle hooks run around AddProfile, UpdateCurrentProfile, SetDefaultProfile,
+// DeleteProfile and UseProfile. Hooks run in the order they are registered.
+//
+// Example:
+//
+//	New("example_app", WithHooks(Hooks{
+//		Ops:   []string{OpUpdateProfile, OpDeleteProfile},
+//		After: func(e HookEvent) error { return tokenCache.Invalidate(e.Profile) },
+//	}))
+func WithHooks(hooks ...Hooks) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.hooks = append(c.hooks, hooks...)
+		return c
+	}
+}
+
+// beforeHooks runs the Before hooks of an operation, stopping at the first veto
+func (p *Profiler) beforeHooks(event HookEvent) error {
+	for _, h := range p.config.hooks {
+		if h.Before == nil || !h.appliesTo(event.Op) {
+			continue
+		}
+		if err := h.Before(event); err != nil {
+			return fmt.Errorf("%w: %w", ErrOperationVetoed, err)
+		}
+	}
+	return nil
+}
+
+// afterHooks runs the After hooks of an operation, stopping at the first error
+func (p *Profiler) afterHooks(event HookEvent) error {
+	for _, h := range p.config.hooks {
+		if h.After == nil || !h.appliesTo(event.Op) {
+			continue
+		}
+		if err := h.After(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Hooks) appliesTo(op string) bool {
+	return len(h.Ops) == 0 || slices.Contains(h.Ops, op)
+}
+
+// prepareSave calls the BeforeSave and Validate methods of a profile implementing them
+func prepareSave(profile NamedProfile) error {
+	if s, ok := profile.(BeforeSaver); ok {
+		s.BeforeSave()
+	}
+	if v, ok := profile.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrProfileInvalid, err)
+		}
+	}
+	return nil
+}
+
+// prepareStoredFields decodes the fields of a profile as T, merged over the data of the profiles
+// it inherits from (root first), and runs the checks of ProfileStore.Save on it: its BeforeSave and
+// Validate methods and the schema. It returns the fields to store, which are those that differ from
+// the inherited data when there is any.
+func prepareStoredFields[T NamedProfile](p *Profiler, fields map[string]json.RawMessage, inherited [][]byte) (store.PartialValue, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return store.PartialValue{}, err
+	}
+	base := map[string]json.RawMessage{}
+	for _, parentData := range inherited {
+		if base, err = mergeJSON(base, parentData); err != nil {
+			return store.PartialValue{}, err
+		}
+	}
+	merged, err := mergeJSON(base, data)
+	if err != nil {
+		return store.PartialValue{}, err
+	}
+	if data, err = json.Marshal(merged); err != nil {
+		return store.PartialValue{}, err
+	}
+
+	var profile T
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return store.PartialValue{}, fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+	}
+	if err := prepareSave(profile); err != nil {
+		return store.PartialValue{}, err
+	}
+	if data, err = json.Marshal(profile); err != nil {
+		return store.PartialValue{}, err
+	}
+	if err := validateSchema(p.config.compiledSchema, data); err != nil {
+		return store.PartialValue{}, err
+	}
+
+	if len(inherited) > 0 {
+		inheritedData, err := json.Marshal(base)
+		if err != nil {
+			return store.PartialValue{}, err
+		}
+		return inheritedDiff(inheritedData, profile)
+	}
+	var prepared map[string]json.RawMessage
+	if err := json.Unmarshal(data, &prepared); err != nil {
+		return store.PartialValue{}, err
+	}
+	return store.PartialValue{Fields: prepared, Type: reflect.TypeFor[T]()}, nil
+}
+
+// storedAncestorData returns the stored data of a profile's ancestors, root first
+func (p *Profiler) storedAncestorData(profileName string) ([][]byte, error) {
+	ancestors, err := p.profileAncestors(profileName)
+	if err != nil {
+		return nil, err
+	}
+	newStore := newStoreFactory(p.config)
+	data := make([][]byte, 0, len(ancestors))
+	for _, ancestor := range ancestors {
+		s, err := newStore(p.config.configName, getStoreKey(ancestor))
+		if err != nil {
+			return nil, err
+		}
+		ancestorData, err := s.Get()
+		if err != nil {
+			return nil, mapStoreError(err)
+		}
+		data = append(data, ancestorData)
+	}
+	return data, nil
+}
+
+// afterLoad calls the AfterLoad method of a profile implementing it, with a value or pointer receiver
+func afterLoad[T any](profile *T) {
+	if l, ok := any(*profile).(AfterLoader); ok {
+		l.AfterLoad()
+	} else if l, ok := any(profile).(AfterLoader); ok {
+		l.AfterLoad()
+	}
+}
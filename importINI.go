@@ -0,0 +1,254 @@
+package profiles
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+// INITagName is the struct tag naming the INI key of a profile field, e.g. `ini:"aws_region"`.
+const INITagName = "ini"
+
+// INIImportOptions configure ImportINI.
+type INIImportOptions[T NamedProfile] struct {
+	// Mapping maps INI keys to the JSON field names of T. Keys without a mapping are matched against
+	// the `ini` struct tag and then the JSON name of each field of T, ignoring case. Keys matching no
+	// field are ignored.
+	Mapping map[string]string
+	// NewProfile returns an empty profile with the given name that the section keys are decoded into.
+	// When nil, the name is set on the field with the JSON name "name".
+	NewProfile func(name string) T
+	// SetDefault sets the profile imported from the [default] section as the default profile.
+	SetDefault bool
+}
+
+// iniSection is a parsed INI section with its keys in file order
+type iniSection struct {
+	name string
+	keys [][2]string
+}
+
+var invalidProfileNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// NormalizeProfileName converts an external name, such as an INI section or kubeconfig context,
+// into a valid profile name by lowercasing it and replacing unsupported characters with dashes.
+func NormalizeProfileName(name string) (string, error) {
+	normalized := invalidProfileNameChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	normalized = strings.Trim(normalized, "-_")
+	if err := validateProfileName(normalized); err != nil {
+		return "", fmt.Errorf("%w: %q", err, name)
+	}
+	return normalized, nil
+}
+
+// ImportINI adds a profile of type T for each section of an AWS-style INI file, such as
+// ~/.aws/config with `[profile name]` sections or ~/.aws/credentials with `[name]` sections.
+// Section names are normalized with NormalizeProfileName and keys are mapped onto T as described
+// by INIImportOptions. Sections that cannot be imported, including existing names reported with
+// ErrProfileNameConflict, are skipped and returned with their error; other sections are still added.
+func ImportINI[T NamedProfile](p *Profiler, r io.Reader, opts INIImportOptions[T]) ([]ImportResult, error) {
+	sections, err := parseINI(r)
+	if err != nil {
+		return nil, p.newProfileError(OpImportProfiles, "", err)
+	}
+
+	results := make([]ImportResult, 0, len(sections))
+	for _, section := range sections {
+		result := ImportResult{Name: section.name, Action: ImportSkipped}
+		profileName, ok := iniProfileName(section.name)
+		if !ok {
+			result.Err = fmt.Errorf("%w: section [%s] is not a profile", ErrUnsupportedFormat, section.name)
+			results = append(results, result)
+			continue
+		}
+		if result.ImportedAs, err = NormalizeProfileName(profileName); err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+
+		profile, err := iniProfile(section, result.ImportedAs, opts)
+		if err == nil {
+			err = p.AddProfile(profile, opts.SetDefault && profileName == "default")
+		}
+		if err != nil {
+			result.Err = err
+		} else {
+			result.Action = ImportAdded
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// iniProfileName returns the profile name of a section, skipping non-profile sections of
+// AWS config files such as [sso-session name] and [services name]
+func iniProfileName(section string) (string, bool) {
+	kind, name, found := strings.Cut(section, " ")
+	if !found {
+		return section, true
+	}
+	return strings.TrimSpace(name), kind == "profile"
+}
+
+// iniProfile decodes the keys of an INI section into a new profile named profileName
+func iniProfile[T NamedProfile](section iniSection, profileName string, opts INIImportOptions[T]) (T, error) {
+	var profile T
+	profileType := reflect.TypeFor[T]()
+	structType := profileType
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return profile, fmt.Errorf("%w: %s is not a struct", ErrProfileTypeMismatch, profileType)
+	}
+
+	fields := make(map[string]json.RawMessage, len(section.keys)+1)
+	if opts.NewProfile == nil {
+		name, err := json.Marshal(profileName)
+		if err != nil {
+			return profile, err
+		}
+		fields["name"] = name
+	}
+	for _, kv := range section.keys {
+		field, ok := iniField(structType, kv[0], opts.Mapping)
+		if !ok {
+			continue
+		}
+		value, err := iniValue(field.Type, kv[1])
+		if err != nil {
+			return profile, fmt.Errorf("%w: section [%s] key %q: %w", ErrUnsupportedFormat, section.name, kv[0], err)
+		}
+		fields[store.JSONFieldName(field)] = value
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return profile, err
+	}
+
+	if opts.NewProfile != nil {
+		profile = opts.NewProfile(profileName)
+	} else if profileType.Kind() == reflect.Pointer {
+		profile = reflect.New(profileType.Elem()).Interface().(T)
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, err
+	}
+	if profile.GetName() != profileName {
+		return profile, fmt.Errorf("%w: decoded profile is named %q, expected %q", ErrProfileTypeMismatch, profile.GetName(), profileName)
+	}
+	return profile, nil
+}
+
+// iniField returns the struct field of t the INI key maps to
+func iniField(t reflect.Type, key string, mapping map[string]string) (reflect.StructField, bool) {
+	jsonName, mapped := mapping[key]
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		switch {
+		case mapped:
+			if store.JSONFieldName(field) == jsonName {
+				return field, true
+			}
+		case strings.EqualFold(field.Tag.Get(INITagName), key), strings.EqualFold(store.JSONFieldName(field), key):
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// iniValue converts an INI string value into the JSON encoding of a value of type t
+func iniValue(t reflect.Type, value string) (json.RawMessage, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var v any = value
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		v = b
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		n := json.Number(value)
+		if _, err := n.Float64(); err != nil {
+			return nil, err
+		}
+		v = n
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v = items
+	}
+	return json.Marshal(v)
+}
+
+// parseINI reads the sections of an INI file. Comments start with ';' or '#', and indented
+// lines following a key with an empty value are nested keys, e.g. "s3.max_concurrent_requests".
+func parseINI(r io.Reader) ([]iniSection, error) {
+	var (
+		sections []iniSection
+		parent   string
+	)
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%w: line %d: unterminated section", ErrUnsupportedFormat, lineNo)
+			}
+			sections = append(sections, iniSection{name: strings.TrimSpace(line[1 : len(line)-1])})
+			parent = ""
+			continue
+		}
+		if len(sections) == 0 {
+			return nil, fmt.Errorf("%w: line %d: key outside of a section", ErrUnsupportedFormat, lineNo)
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: line %d: expected key = value", ErrUnsupportedFormat, lineNo)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		switch {
+		case indented && parent != "":
+			key = parent + "." + key
+		case value == "":
+			parent = key
+			continue
+		default:
+			parent = ""
+		}
+		section := &sections[len(sections)-1]
+		section.keys = append(section.keys, [2]string{key, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Join(ErrUnsupportedFormat, err)
+	}
+	return sections, nil
+}
@@ -0,0 +1,181 @@
+package profiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+// SetProfileParent makes the specified profile inherit from parent. Loading the profile returns
+// the parent's values deep-merged with its own, and saving it stores only the fields that differ
+// from the inherited values. Fields absent or null in a profile's stored JSON (e.g. `omitempty`)
+// are inherited. An empty parent removes the inheritance.
+func SetProfileParent(p *Profiler, profileName, parent string) error {
+	if !p.globalStore.ProfileExists(profileName) {
+		return p.newProfileError(OpUpdateProfile, profileName, ErrMissingProfileName)
+	}
+	if parent != "" {
+		if !p.globalStore.ProfileExists(parent) {
+			return p.newProfileError(OpUpdateProfile, profileName, fmt.Errorf("%w: parent %q", ErrMissingProfileName, parent))
+		}
+		ancestors, err := p.profileAncestors(parent)
+		if err != nil {
+			return p.newProfileError(OpUpdateProfile, profileName, err)
+		}
+		for _, ancestor := range append(ancestors, parent) {
+			if ancestor == profileName {
+				return p.newProfileError(OpUpdateProfile, profileName, fmt.Errorf("%w: %q inherits from %q", ErrProfileInheritanceCycle, parent, profileName))
+			}
+		}
+	}
+	if err := p.updateProfileInfo(profileName, func(info *ProfileInfo) {
+		info.Parent = parent
+	}); err != nil {
+		return err
+	}
+
+	// The current profile no longer reflects the values it inherits
+	if p.currentProfileStore != nil && p.currentProfileStore.GetProfileName() == profileName {
+		p.currentProfileStore = nil
+	}
+	return nil
+}
+
+// ListProfileChildren returns the names of the profiles directly inheriting from the specified profile
+func ListProfileChildren(p *Profiler, profileName string) []string {
+	return ListProfilesFunc(p, func(info ProfileInfo) bool {
+		return info.Parent == profileName
+	})
+}
+
+// profileAncestors returns the profiles the specified profile inherits from, root first
+func (p *Profiler) profileAncestors(profileName string) ([]string, error) {
+	var ancestors []string
+	seen := map[string]bool{profileName: true}
+	for parent := p.globalStore.GetProfileInfo(profileName).Parent; parent != ""; parent = p.globalStore.GetProfileInfo(parent).Parent {
+		if seen[parent] {
+			return nil, fmt.Errorf("%w: %q", ErrProfileInheritanceCycle, parent)
+		}
+		if !p.globalStore.ProfileExists(parent) {
+			return nil, fmt.Errorf("%w: parent %q", ErrMissingProfileName, parent)
+		}
+		seen[parent] = true
+		ancestors = append([]string{parent}, ancestors...)
+	}
+	return ancestors, nil
+}
+
+// mergeInherited merges the data of the parent stores, root first, and then data
+func (p *ProfileStore) mergeInherited(data []byte) ([]byte, error) {
+	inherited := map[string]json.RawMessage{}
+	for _, parent := range p.parents {
+		parentData, err := parent.Get()
+		if err != nil {
+			return nil, mapStoreError(err)
+		}
+		if inherited, err = mergeJSON(inherited, parentData); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if p.inherited, err = json.Marshal(inherited); err != nil {
+		return nil, err
+	}
+	merged, err := mergeJSON(inherited, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merged)
+}
+
+// mergeJSON deep-merges the JSON object overlay into base. Nested objects are merged, null
+// values are ignored and any other value replaces the base value.
+func mergeJSON(base map[string]json.RawMessage, overlay []byte) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(overlay, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+	}
+	merged := make(map[string]json.RawMessage, len(base)+len(fields))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		switch {
+		case isJSONNull(v):
+			continue
+		case isJSONObject(v) && isJSONObject(merged[k]):
+			var nested map[string]json.RawMessage
+			if err := json.Unmarshal(merged[k], &nested); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+			}
+			nested, err := mergeJSON(nested, v)
+			if err != nil {
+				return nil, err
+			}
+			if merged[k], err = json.Marshal(nested); err != nil {
+				return nil, err
+			}
+		default:
+			merged[k] = v
+		}
+	}
+	return merged, nil
+}
+
+// inheritedDiff returns the fields of profile that differ from the inherited data
+func inheritedDiff(inherited json.RawMessage, profile NamedProfile) (store.PartialValue, error) {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return store.PartialValue{}, err
+	}
+	fields, err := diffJSON(inherited, data)
+	if err != nil {
+		return store.PartialValue{}, err
+	}
+	return store.PartialValue{Fields: fields, Type: reflect.TypeOf(profile)}, nil
+}
+
+// diffJSON returns the fields of the JSON object value that are absent from or differ from base,
+// descending into nested objects
+func diffJSON(base, value []byte) (map[string]json.RawMessage, error) {
+	var baseFields, fields map[string]json.RawMessage
+	if err := json.Unmarshal(base, &baseFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return nil, err
+	}
+	diff := make(map[string]json.RawMessage)
+	for k, v := range fields {
+		b, ok := baseFields[k]
+		switch {
+		case !ok:
+			diff[k] = v
+		case isJSONObject(b) && isJSONObject(v):
+			nested, err := diffJSON(b, v)
+			if err != nil {
+				return nil, err
+			}
+			if len(nested) > 0 {
+				if diff[k], err = json.Marshal(nested); err != nil {
+					return nil, err
+				}
+			}
+		case !jsonEqual(b, v):
+			diff[k] = v
+		}
+	}
+	return diff, nil
+}
+
+func isJSONObject(v json.RawMessage) bool {
+	v = bytes.TrimSpace(v)
+	return len(v) > 0 && v[0] == '{'
+}
+
+func isJSONNull(v json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(v), []byte("null"))
+}
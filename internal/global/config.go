@@ -2,20 +2,26 @@ package global
 
 import (
 	"encoding/json"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/jrschumacher/go-osprofiles/pkg/store"
 )
 
 // Define constants for the different storage drivers and store keys
 const (
-	PROFILE_DRIVER_KEYRING   ProfileDriver = "keyring"
-	PROFILE_DRIVER_IN_MEMORY ProfileDriver = "in-memory"
-	PROFILE_DRIVER_FILE      ProfileDriver = "file"
+	PROFILE_DRIVER_KEYRING    ProfileDriver = "keyring"
+	PROFILE_DRIVER_IN_MEMORY  ProfileDriver = "in-memory"
+	PROFILE_DRIVER_FILE       ProfileDriver = "file"
+	PROFILE_DRIVER_PLAIN_FILE ProfileDriver = "plain-file"
 	// Experimental: enables definition of custom storage driver
 	PROFILE_DRIVER_CUSTOM  ProfileDriver = "custom"
 	PROFILE_DRIVER_DEFAULT               = PROFILE_DRIVER_FILE
 	STORE_KEY_PROFILE                    = "profile"
 	STORE_KEY_GLOBAL                     = "global"
+	STORE_KEY_PROBE                      = "probe"
+	STORE_KEY_REVISION                   = "revision"
 )
 
 type ProfileDriver string
@@ -33,9 +39,48 @@ type GlobalStore struct {
 }
 
 type GlobalConfig struct {
-	ProfilesVersion string   `json:"version"`
-	Profiles        []string `json:"profiles"`
-	DefaultProfile  string   `json:"defaultProfile"`
+	ProfilesVersion string                 `json:"version"`
+	Profiles        []string               `json:"profiles"`
+	DefaultProfile  string                 `json:"defaultProfile"`
+	ProfileInfo     map[string]ProfileInfo `json:"profileInfo,omitempty"`
+	// LockDefaultProfile, set by administrators in a system-wide configuration, enforces DefaultProfile
+	LockDefaultProfile bool `json:"lockDefaultProfile,omitempty"`
+	// Trash holds soft-deleted profiles by name
+	Trash map[string]DeletedProfile `json:"trash,omitempty"`
+}
+
+// DeletedProfile is a soft-deleted profile kept in the trash along with its metadata.
+type DeletedProfile struct {
+	// Name of the profile, populated from the key it is stored under
+	Name      string      `json:"-"`
+	DeletedAt time.Time   `json:"deletedAt"`
+	Info      ProfileInfo `json:"info"`
+}
+
+// ProfileInfo is library-managed metadata about a stored profile.
+type ProfileInfo struct {
+	// Name of the profile, populated from the key it is stored under
+	Name        string    `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	LastUsedAt  time.Time `json:"lastUsedAt"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	// Parent is the name of the profile this profile inherits from
+	Parent string `json:"parent,omitempty"`
+	// History lists the stored revisions of the profile, oldest first
+	History []ProfileRevision `json:"history,omitempty"`
+}
+
+// ProfileRevision is a previous version of a profile kept in its history.
+type ProfileRevision struct {
+	Revision int       `json:"revision"`
+	SavedAt  time.Time `json:"savedAt"`
+}
+
+// HasTag returns true if the profile is tagged with tag.
+func (i ProfileInfo) HasTag(tag string) bool {
+	return slices.Contains(i.Tags, tag)
 }
 
 // LoadGlobalConfig loads the global configuration from the store for the given name of the configuration being stored.
@@ -106,9 +151,37 @@ func (p *GlobalStore) ProfileExists(profileName string) bool {
 
 func (p *GlobalStore) AddProfile(profileName string) error {
 	p.config.Profiles = append(p.config.Profiles, profileName)
+	now := time.Now().UTC()
+	p.setProfileInfo(profileName, ProfileInfo{CreatedAt: now, UpdatedAt: now})
 	return p.store.Set(p.config)
 }
 
+// GetProfileInfo returns the metadata of a profile. Profiles created before metadata was
+// recorded return an empty ProfileInfo.
+func (p *GlobalStore) GetProfileInfo(profileName string) ProfileInfo {
+	info := p.config.ProfileInfo[profileName]
+	info.Name = profileName
+	info.Tags = slices.Clone(info.Tags)
+	info.History = slices.Clone(info.History)
+	return info
+}
+
+// UpdateProfileInfo applies update to the metadata of a profile and persists it.
+func (p *GlobalStore) UpdateProfileInfo(profileName string, update func(*ProfileInfo)) error {
+	info := p.GetProfileInfo(profileName)
+	update(&info)
+	p.setProfileInfo(profileName, info)
+	return p.store.Set(p.config)
+}
+
+func (p *GlobalStore) setProfileInfo(profileName string, info ProfileInfo) {
+	if p.config.ProfileInfo == nil {
+		p.config.ProfileInfo = make(map[string]ProfileInfo)
+	}
+	info.Name = ""
+	p.config.ProfileInfo[profileName] = info
+}
+
 func (p *GlobalStore) ListProfiles() []string {
 	return p.config.Profiles
 }
@@ -135,6 +208,7 @@ func (p *GlobalStore) remove(profileName string) error {
 	for i, profile := range p.config.Profiles {
 		if profile == profileName {
 			p.config.Profiles = append(p.config.Profiles[:i], p.config.Profiles[i+1:]...)
+			delete(p.config.ProfileInfo, profileName)
 			return p.store.Set(p.config)
 		}
 	}
@@ -142,6 +216,78 @@ func (p *GlobalStore) remove(profileName string) error {
 	return nil
 }
 
+// TrashProfile moves a profile from the list of profiles to the trash, keeping its metadata.
+// The default profile is unset if it is the trashed profile.
+func (p *GlobalStore) TrashProfile(profileName string) error {
+	i := slices.Index(p.config.Profiles, profileName)
+	if i < 0 {
+		return nil
+	}
+	if p.config.Trash == nil {
+		p.config.Trash = make(map[string]DeletedProfile)
+	}
+	p.config.Trash[profileName] = DeletedProfile{
+		DeletedAt: time.Now().UTC(),
+		Info:      p.GetProfileInfo(profileName),
+	}
+	p.config.Profiles = slices.Delete(p.config.Profiles, i, i+1)
+	delete(p.config.ProfileInfo, profileName)
+	if profileName == p.config.DefaultProfile {
+		p.config.DefaultProfile = ""
+	}
+	return p.store.Set(p.config)
+}
+
+// ListTrash returns the soft-deleted profiles sorted by name.
+func (p *GlobalStore) ListTrash() []DeletedProfile {
+	trash := make([]DeletedProfile, 0, len(p.config.Trash))
+	for _, name := range slices.Sorted(maps.Keys(p.config.Trash)) {
+		deleted := p.config.Trash[name]
+		deleted.Name = name
+		deleted.Info.Name = name
+		trash = append(trash, deleted)
+	}
+	return trash
+}
+
+// GetDeletedProfile returns a profile in the trash.
+func (p *GlobalStore) GetDeletedProfile(profileName string) (DeletedProfile, bool) {
+	deleted, ok := p.config.Trash[profileName]
+	if !ok {
+		return DeletedProfile{}, false
+	}
+	deleted.Name = profileName
+	deleted.Info.Name = profileName
+	return deleted, true
+}
+
+// InTrash returns true if the profile is in the trash.
+func (p *GlobalStore) InTrash(profileName string) bool {
+	_, ok := p.config.Trash[profileName]
+	return ok
+}
+
+// RestoreFromTrash moves a profile from the trash back to the list of profiles.
+func (p *GlobalStore) RestoreFromTrash(profileName string) error {
+	deleted, ok := p.config.Trash[profileName]
+	if !ok {
+		return nil
+	}
+	p.config.Profiles = append(p.config.Profiles, profileName)
+	p.setProfileInfo(profileName, deleted.Info)
+	delete(p.config.Trash, profileName)
+	return p.store.Set(p.config)
+}
+
+// RemoveFromTrash forgets a profile in the trash.
+func (p *GlobalStore) RemoveFromTrash(profileName string) error {
+	if _, ok := p.config.Trash[profileName]; !ok {
+		return nil
+	}
+	delete(p.config.Trash, profileName)
+	return p.store.Set(p.config)
+}
+
 func (p *GlobalStore) SetDefaultProfile(profileName string) error {
 	p.config.DefaultProfile = profileName
 	return p.store.Set(p.config)
@@ -0,0 +1,8 @@
+package importers
+
+import "errors"
+
+var (
+	ErrInvalidFormat = errors.New("error: invalid import file format")
+	ErrEntrySkipped  = errors.New("error: entry cannot be imported as a profile")
+)
@@ -0,0 +1,43 @@
+// Package importers seeds profiles from the configuration files of other tools, such as
+// kubeconfig contexts and .netrc machines. Entries are converted by a caller-provided function
+// into the application's profile type and added through the Profiler.
+package importers
+
+import (
+	"fmt"
+
+	profiles "github.com/jrschumacher/go-osprofiles"
+)
+
+// addProfiles adds a profile for each entry under its normalized name. Entries that cannot be
+// converted or added, including name collisions reported with profiles.ErrProfileNameConflict,
+// are skipped and reported with their error.
+func addProfiles[E any, T profiles.NamedProfile](p *profiles.Profiler, entries []E, entryName func(E) string, newProfile func(string, E) (T, error)) []profiles.ImportResult {
+	results := make([]profiles.ImportResult, 0, len(entries))
+	for _, entry := range entries {
+		result := profiles.ImportResult{Name: entryName(entry), Action: profiles.ImportSkipped}
+		name, err := profiles.NormalizeProfileName(result.Name)
+		if err == nil {
+			result.ImportedAs = name
+			err = addProfile(p, name, entry, newProfile)
+		}
+		if err != nil {
+			result.Err = err
+		} else {
+			result.Action = profiles.ImportAdded
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func addProfile[E any, T profiles.NamedProfile](p *profiles.Profiler, name string, entry E, newProfile func(string, E) (T, error)) error {
+	profile, err := newProfile(name, entry)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrEntrySkipped, err)
+	}
+	if profile.GetName() != name {
+		return fmt.Errorf("%w: profile is named %q, expected %q", ErrEntrySkipped, profile.GetName(), name)
+	}
+	return p.AddProfile(profile, false)
+}
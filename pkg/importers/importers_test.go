@@ -0,0 +1,120 @@
+package importers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	profiles "github.com/jrschumacher/go-osprofiles"
+	"github.com/stretchr/testify/require"
+)
+
+type mockProfile struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	User     string `json:"user"`
+	Token    string `json:"token" osprofiles:"secret"`
+}
+
+func (p *mockProfile) GetName() string {
+	return p.Name
+}
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+users:
+- name: admin
+  user:
+    token: s3cr3t
+contexts:
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: admin
+    namespace: default
+- name: Prod
+  context:
+    cluster: prod-cluster
+- name: broken
+  context:
+    cluster: missing
+`
+
+const testNetrc = `# credentials
+machine api.example.com login alice password hunter2
+machine git.example.com
+  login bob
+  password s3cr3t
+  account team
+
+default login anonymous password guest
+macdef init
+cd /pub
+
+machine skip.example.com login carol
+`
+
+func TestImportKubeConfig(t *testing.T) {
+	contexts, err := ReadKubeConfig(strings.NewReader(testKubeConfig))
+	require.NoError(t, err)
+	require.Equal(t, KubeContext{
+		Name:      "prod",
+		Cluster:   "prod-cluster",
+		Server:    "https://prod.example.com",
+		User:      "admin",
+		Token:     "s3cr3t",
+		Namespace: "default",
+		Current:   true,
+	}, contexts[0])
+
+	profiler, err := profiles.New("test-import-kubeconfig", profiles.WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	results, err := ImportKubeConfig(profiler, strings.NewReader(testKubeConfig), func(name string, c KubeContext) (*mockProfile, error) {
+		return &mockProfile{Name: name, Endpoint: c.Server, User: c.User, Token: c.Token}, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, profiles.ImportResult{Name: "prod", ImportedAs: "prod", Action: profiles.ImportAdded}, results[0])
+	require.ErrorIs(t, results[1].Err, profiles.ErrProfileNameConflict)
+	require.ErrorIs(t, results[2].Err, ErrEntrySkipped)
+
+	prod, err := profiles.NewTypedProfiler[*mockProfile](profiler).Get("prod")
+	require.NoError(t, err)
+	require.Equal(t, &mockProfile{Name: "prod", Endpoint: "https://prod.example.com", User: "admin", Token: "s3cr3t"}, prod)
+
+	_, err = ReadKubeConfig(strings.NewReader("contexts: {"))
+	require.ErrorIs(t, err, ErrInvalidFormat)
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestImportNetrc(t *testing.T) {
+	machines, err := ReadNetrc(strings.NewReader(testNetrc))
+	require.NoError(t, err)
+	require.Equal(t, []NetrcMachine{
+		{Machine: "api.example.com", Login: "alice", Password: "hunter2"},
+		{Machine: "git.example.com", Login: "bob", Password: "s3cr3t", Account: "team"},
+		{Machine: "skip.example.com", Login: "carol"},
+	}, machines)
+
+	profiler, err := profiles.New("test-import-netrc", profiles.WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	results, err := ImportNetrc(profiler, strings.NewReader(testNetrc), func(name string, m NetrcMachine) (*mockProfile, error) {
+		if m.Password == "" {
+			return nil, errors.New("no password")
+		}
+		return &mockProfile{Name: name, Endpoint: "https://" + m.Machine, User: m.Login, Token: m.Password}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"api-example-com", "git-example-com"}, profiles.ListProfiles(profiler))
+	require.Equal(t, profiles.ImportSkipped, results[2].Action)
+	require.ErrorIs(t, results[2].Err, ErrEntrySkipped)
+
+	_, err = ReadNetrc(strings.NewReader("machine"))
+	require.ErrorIs(t, err, ErrInvalidFormat)
+	require.NoError(t, profiler.Cleanup(true))
+}
@@ -0,0 +1,110 @@
+package importers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	profiles "github.com/jrschumacher/go-osprofiles"
+	"gopkg.in/yaml.v3"
+)
+
+// KubeContext is a context of a kubeconfig file with its cluster and user resolved.
+type KubeContext struct {
+	Name      string
+	Cluster   string
+	Server    string
+	User      string
+	Token     string
+	Namespace string
+	// Current is true for the current-context of the kubeconfig.
+	Current bool
+}
+
+// kubeConfig is the subset of a kubeconfig file read by ReadKubeConfig
+type kubeConfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server string `yaml:"server"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token string `yaml:"token"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// DefaultKubeConfigPath returns the first file listed in $KUBECONFIG, or ~/.kube/config.
+func DefaultKubeConfigPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// ReadKubeConfig returns the contexts of a kubeconfig file in file order.
+func ReadKubeConfig(r io.Reader) ([]KubeContext, error) {
+	var config kubeConfig
+	if err := yaml.NewDecoder(r).Decode(&config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+
+	servers := make(map[string]string, len(config.Clusters))
+	for _, c := range config.Clusters {
+		servers[c.Name] = c.Cluster.Server
+	}
+	tokens := make(map[string]string, len(config.Users))
+	for _, u := range config.Users {
+		tokens[u.Name] = u.User.Token
+	}
+
+	contexts := make([]KubeContext, 0, len(config.Contexts))
+	for _, c := range config.Contexts {
+		contexts = append(contexts, KubeContext{
+			Name:      c.Name,
+			Cluster:   c.Context.Cluster,
+			Server:    servers[c.Context.Cluster],
+			User:      c.Context.User,
+			Token:     tokens[c.Context.User],
+			Namespace: c.Context.Namespace,
+			Current:   c.Name == config.CurrentContext,
+		})
+	}
+	return contexts, nil
+}
+
+// ImportKubeConfig adds a profile for each context of a kubeconfig file, named after the context
+// normalized with profiles.NormalizeProfileName. newProfile converts a context into a profile
+// with the given name, or returns an error to skip it. Contexts whose cluster has no server are
+// skipped. Skipped contexts and name collisions (profiles.ErrProfileNameConflict) are reported
+// in the results without stopping the import.
+func ImportKubeConfig[T profiles.NamedProfile](p *profiles.Profiler, r io.Reader, newProfile func(name string, context KubeContext) (T, error)) ([]profiles.ImportResult, error) {
+	contexts, err := ReadKubeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	return addProfiles(p, contexts, func(c KubeContext) string { return c.Name }, func(name string, c KubeContext) (T, error) {
+		if c.Server == "" {
+			var zero T
+			return zero, fmt.Errorf("context %q has no cluster server", c.Name)
+		}
+		return newProfile(name, c)
+	}), nil
+}
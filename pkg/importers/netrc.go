@@ -0,0 +1,113 @@
+package importers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	profiles "github.com/jrschumacher/go-osprofiles"
+)
+
+// NetrcMachine is a machine entry of a .netrc file.
+type NetrcMachine struct {
+	Machine  string
+	Login    string
+	Password string
+	Account  string
+}
+
+// DefaultNetrcPath returns $NETRC, or ~/.netrc (~/_netrc on Windows).
+func DefaultNetrcPath() (string, error) {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc"), nil
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+// ReadNetrc returns the machine entries of a .netrc file in file order. The default entry,
+// which has no machine name, and macro definitions are ignored.
+func ReadNetrc(r io.Reader) ([]NetrcMachine, error) {
+	var (
+		machines []NetrcMachine
+		current  *NetrcMachine
+		inMacro  bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// a macro definition runs until the next blank line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		tokens := strings.Fields(line)
+		for i := 0; i < len(tokens); i++ {
+			token := tokens[i]
+			switch token {
+			case "default":
+				current = nil
+				continue
+			case "macdef":
+				inMacro = true
+				i = len(tokens)
+				continue
+			}
+
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("%w: .netrc token %q has no value", ErrInvalidFormat, token)
+			}
+			i++
+			value := tokens[i]
+			if token == "machine" {
+				machines = append(machines, NetrcMachine{Machine: value})
+				current = &machines[len(machines)-1]
+				continue
+			}
+			if current == nil {
+				continue
+			}
+			switch token {
+			case "login":
+				current.Login = value
+			case "password":
+				current.Password = value
+			case "account":
+				current.Account = value
+			default:
+				return nil, fmt.Errorf("%w: unknown .netrc token %q", ErrInvalidFormat, token)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFormat, err)
+	}
+	return machines, nil
+}
+
+// ImportNetrc adds a profile for each machine of a .netrc file, named after the machine normalized
+// with profiles.NormalizeProfileName (e.g. "api.example.com" becomes "api-example-com"). newProfile
+// converts a machine into a profile with the given name, or returns an error to skip it. Skipped
+// machines and name collisions (profiles.ErrProfileNameConflict) are reported in the results
+// without stopping the import.
+func ImportNetrc[T profiles.NamedProfile](p *profiles.Profiler, r io.Reader, newProfile func(name string, machine NetrcMachine) (T, error)) ([]profiles.ImportResult, error) {
+	machines, err := ReadNetrc(r)
+	if err != nil {
+		return nil, err
+	}
+	return addProfiles(p, machines, func(m NetrcMachine) string { return m.Machine }, newProfile), nil
+}
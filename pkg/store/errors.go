@@ -2,11 +2,24 @@ package store
 
 import (
 	"errors"
+	"io/fs"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Errors wrapped by every built-in driver so callers can tell a missing entry from a broken one.
+// Corrupt data is reported as ErrStoredValueInvalid.
+var (
+	ErrNotFound         = errors.New("error: entry not found in store")
+	ErrPermissionDenied = errors.New("error: permission denied accessing store")
+	ErrDecryptionFailed = errors.New("error: decrypting stored value failed")
 )
 
 var (
 	ErrStoredValueInvalid   = errors.New("error: invalid stored value")
 	ErrEncryptedDataInvalid = errors.New("error: invalid encrypted data")
+	ErrCipherUnsupported    = errors.New("error: unsupported cipher")
+	ErrPassphraseRequired   = errors.New("error: passphrase required")
 
 	ErrNamespaceInvalid   = errors.New("error: invalid namespace")
 	ErrKeyInvalid         = errors.New("error: invalid namespace")
@@ -14,6 +27,27 @@ var (
 	ErrValueBadCharacters = errors.New("error: value contains invalid characters")
 
 	ErrLengthExceeded = errors.New("error: length exceeded")
+	ErrValueTooLarge  = errors.New("error: value too large for store")
 
 	ErrStoreDriverSetup = errors.New("error: store driver setup failed")
 )
+
+// wrapFileError wraps file system errors with the matching driver-agnostic error
+func wrapFileError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return errors.Join(ErrNotFound, err)
+	case errors.Is(err, fs.ErrPermission):
+		return errors.Join(ErrPermissionDenied, err)
+	default:
+		return err
+	}
+}
+
+// wrapKeyringError wraps keyring errors with the matching driver-agnostic error
+func wrapKeyringError(err error) error {
+	if errors.Is(err, keyring.ErrNotFound) {
+		return errors.Join(ErrNotFound, err)
+	}
+	return err
+}
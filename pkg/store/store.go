@@ -6,9 +6,33 @@ import (
 	"regexp"
 )
 
-// DriverOpt is a variadic function to apply any driver-specific options, which
-// apply any side effects/hooks necessary for the driver.
-type DriverOpt func() error
+// DriverOpt is a variadic function to apply any driver-specific options. Options only apply to
+// the store being constructed, so every construction of a store must be passed its options.
+type DriverOpt func(*DriverOptions) error
+
+// DriverOptions hold the settings applied by DriverOpts. Each driver reads the settings it supports,
+// and custom drivers can read them with ApplyDriverOpts.
+type DriverOptions struct {
+	// StoreDirectory is the directory of file-backed stores, see WithStoreDirectory
+	StoreDirectory string
+	// Passphrase derives the file store encryption key, see WithPassphrase
+	Passphrase string
+	// Cipher encrypts file store values, see WithCipher
+	Cipher Cipher
+	// KeyringCompression compresses large keyring values, see WithKeyringCompression
+	KeyringCompression bool
+}
+
+// ApplyDriverOpts returns the settings of the options, starting from the driver defaults
+func ApplyDriverOpts(driverOpts []DriverOpt) (DriverOptions, error) {
+	opts := DriverOptions{Cipher: CipherAES256GCM}
+	for _, opt := range driverOpts {
+		if err := opt(&opts); err != nil {
+			return opts, errors.Join(ErrStoreDriverSetup, err)
+		}
+	}
+	return opts, nil
+}
 
 // StoreInterface is an interface for a store of a single key and value under a namespace.
 // The key is unique within the namespace, and the stored value is a JSON-serialized struct.
@@ -32,6 +56,13 @@ type StoreInterface interface {
 	Delete() error
 }
 
+// FileBackedStore is implemented by stores that persist their value in a file on disk,
+// so callers can watch the file for changes. FilePath returns "" when no file backs the store.
+type FileBackedStore interface {
+	StoreInterface
+	FilePath() string
+}
+
 // NewCustomStore is a package global to init a custom store implementation.
 var NewCustomStore NewStoreInterface
 
@@ -2,9 +2,8 @@ package store
 
 import (
 	"bytes"
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +13,7 @@ import (
 	"time"
 
 	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/argon2"
 )
 
 type fileStore struct {
@@ -21,14 +21,25 @@ type fileStore struct {
 	namespace           string
 	key                 string
 	filePath            string
+	cipher              Cipher
+	// passphrase, when set, derives the encryption key instead of storing it in the keyring
+	passphrase string
+	keySalt    []byte
 }
 
 // Metadata structure for unencrypted metadata about the encrypted file
 type fileMetadata struct {
 	ProfileName   string `json:"profile_name"`
 	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
 	EncryptionAlg string `json:"encryption_alg"`
 	Version       string `json:"version"`
+	KeyDerivation string `json:"key_derivation,omitempty"`
+	KeySalt       string `json:"key_salt,omitempty"`
+
+	// PreviousEncryptionAlg is the cipher of the value being replaced when the cipher changed,
+	// which the value on disk still uses if the write was interrupted
+	PreviousEncryptionAlg string `json:"previous_encryption_alg,omitempty"`
 }
 
 const (
@@ -36,15 +47,27 @@ const (
 	aes256KeyLength     = 32
 	ownerPermissionsRW  = 0o600
 	ownerPermissionsRWX = 0o700
-)
 
-// Directory where profiles are stored when using the fileStore driver
-var storeDirectory string
+	keyDerivationArgon2id = "argon2id"
+	keySaltLength         = 16
+)
 
 // Assigns the store directory for the fileStore driver
 func WithStoreDirectory(storeDir string) DriverOpt {
-	return func() error {
-		storeDirectory = storeDir
+	return func(o *DriverOptions) error {
+		o.StoreDirectory = storeDir
+		return nil
+	}
+}
+
+// WithPassphrase derives the fileStore encryption key from a passphrase (argon2id with a per-file salt)
+// instead of generating a random key kept in the OS keyring. Useful where no keyring is available.
+func WithPassphrase(passphrase string) DriverOpt {
+	return func(o *DriverOptions) error {
+		if passphrase == "" {
+			return ErrPassphraseRequired
+		}
+		o.Passphrase = passphrase
 		return nil
 	}
 }
@@ -63,14 +86,13 @@ var NewFileStore NewStoreInterface = func(serviceNamespace, key string, driverOp
 	}
 
 	// Apply any driver options
-	for _, opt := range driverOpts {
-		if err := opt(); err != nil {
-			return nil, errors.Join(ErrStoreDriverSetup, err)
-		}
+	opts, err := ApplyDriverOpts(driverOpts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Either storeDirectory is set by the WithStoreDirectory option or the "profiles" directory relative to the running executable
-	baseDir := storeDirectory
+	// Either the directory is set by the WithStoreDirectory option or the "profiles" directory relative to the running executable
+	baseDir := opts.StoreDirectory
 	if baseDir == "" {
 		execPath, err := os.Executable()
 		if err != nil {
@@ -85,14 +107,14 @@ var NewFileStore NewStoreInterface = func(serviceNamespace, key string, driverOp
 		panic(fmt.Sprintf("failed to create profiles directory %s: please check directory permissions", baseDir))
 	}
 
-	// Check for read/write permissions by creating and removing a temp file
-	testFilePath := filepath.Join(baseDir, ".tmp_profile_rw_test")
-	testFile, err := os.Create(testFilePath)
+	// Check for read/write permissions by creating and removing a temp file, uniquely named so
+	// stores can be constructed concurrently
+	testFile, err := os.CreateTemp(baseDir, ".tmp_profile_rw_test*")
 	if err != nil {
 		panic(fmt.Sprintf("unable to write to profiles directory %s: please ensure write permissions are granted", baseDir))
 	}
 	testFile.Close()
-	if err := os.Remove(testFilePath); err != nil {
+	if err := os.Remove(testFile.Name()); err != nil {
 		panic(fmt.Sprintf("unable to delete temp file in profiles directory %s: please ensure delete permissions are granted", baseDir))
 	}
 
@@ -104,9 +126,16 @@ var NewFileStore NewStoreInterface = func(serviceNamespace, key string, driverOp
 		namespace:           serviceNamespace,
 		key:                 key,
 		filePath:            filePath,
+		cipher:              opts.Cipher,
+		passphrase:          opts.Passphrase,
 	}, nil
 }
 
+// FilePath returns the path of the encrypted file
+func (f *fileStore) FilePath() string {
+	return f.filePath
+}
+
 // Exists checks if the encrypted file exists
 func (f *fileStore) Exists() bool {
 	_, err := os.Stat(f.filePath)
@@ -115,20 +144,58 @@ func (f *fileStore) Exists() bool {
 
 // Get retrieves and decrypts data from the file
 func (f *fileStore) Get() ([]byte, error) {
-	key, err := f.getEncryptionKey()
+	metadata, err := f.storedMetadata()
 	if err != nil {
 		return nil, err
 	}
-	encryptedData, err := os.ReadFile(f.filePath)
+	c := f.cipher
+	if metadata.EncryptionAlg != c.Name() {
+		if c, err = lookupCipher(metadata.EncryptionAlg); err != nil {
+			return nil, err
+		}
+	}
+	key, err := f.getEncryptionKey(metadata, c.KeySize())
 	if err != nil {
 		return nil, err
 	}
-	return decryptData(key, encryptedData)
+	encryptedData, err := os.ReadFile(f.filePath)
+	if err != nil {
+		return nil, wrapFileError(err)
+	}
+	data, err := c.Decrypt(key, encryptedData)
+	if err != nil && metadata.PreviousEncryptionAlg != "" {
+		if previous, ok := f.decryptPrevious(metadata, encryptedData); ok {
+			return previous, nil
+		}
+	}
+	if err != nil && !errors.Is(err, ErrStoredValueInvalid) {
+		return nil, errors.Join(ErrDecryptionFailed, err)
+	}
+	return data, err
+}
+
+// decryptPrevious decrypts a value still encrypted with the previous cipher recorded in the
+// metadata, left behind by a write that switched ciphers and was interrupted
+func (f *fileStore) decryptPrevious(metadata *fileMetadata, encryptedData []byte) ([]byte, bool) {
+	c, err := lookupCipher(metadata.PreviousEncryptionAlg)
+	if err != nil {
+		return nil, false
+	}
+	key, err := f.getEncryptionKey(metadata, c.KeySize())
+	if err != nil {
+		return nil, false
+	}
+	data, err := c.Decrypt(key, encryptedData)
+	return data, err == nil
 }
 
 // Set encrypts and saves data to the file, also saving metadata
 func (f *fileStore) Set(value interface{}) error {
-	key, err := f.getEncryptionKey()
+	metadata, err := f.storedMetadata()
+	if err != nil {
+		return err
+	}
+	key, err := f.getEncryptionKey(metadata, f.cipher.KeySize())
 	if err != nil {
 		return err
 	}
@@ -136,36 +203,57 @@ func (f *fileStore) Set(value interface{}) error {
 	if err := json.NewEncoder(&b).Encode(value); err != nil {
 		return err
 	}
-	encryptedData, err := encryptData(key, b.Bytes())
+	encryptedData, err := f.cipher.Encrypt(key, b.Bytes())
 	if err != nil {
 		return err
 	}
+	// Save the metadata first so the key salt of a new value is never lost; it keeps the previous
+	// cipher so the value stays readable if the write below is interrupted
+	profileName := f.key // or extract from value if it's part of a ProfileConfig struct
+	if err := f.SaveMetadata(profileName); err != nil {
+		return err
+	}
 	// Write the encrypted profile file with proper permissions
-	if err := os.WriteFile(f.filePath, encryptedData, ownerPermissionsRW); err != nil {
-		return fmt.Errorf("failed to write encrypted profile to %s: %w", f.filePath, err)
+	if err := writeFileAtomic(f.filePath, encryptedData); err != nil {
+		return fmt.Errorf("failed to write encrypted profile to %s: %w", f.filePath, wrapFileError(err))
 	}
-	// Save metadata as well
-	profileName := f.key // or extract from value if it's part of a ProfileConfig struct
-	return f.SaveMetadata(profileName)
+	return nil
 }
 
-// Delete removes the encrypted file and metadata file from disk
+// Delete removes the encrypted file and metadata file from disk, and the encryption key from the
+// keyring unless the key is derived from a passphrase
 func (f *fileStore) Delete() error {
 	if err := os.Remove(f.filePath); err != nil {
-		return err
+		return wrapFileError(err)
 	}
 	// Remove the extension from filePath and add .nfo for the metadata file
 	metadataFilePath := strings.TrimSuffix(f.filePath, filepath.Ext(f.filePath)) + ".nfo"
-	return os.Remove(metadataFilePath)
+	if err := os.Remove(metadataFilePath); err != nil {
+		return wrapFileError(err)
+	}
+	if f.passphrase != "" {
+		return nil
+	}
+	if err := keyring.Delete(f.namespaceVersionURN, f.key); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+	return nil
 }
 
-// getEncryptionKey retrieves the encryption key from the keyring or generates it if absent
-func (f *fileStore) getEncryptionKey() ([]byte, error) {
+// getEncryptionKey derives the key from the passphrase, or retrieves it from the keyring and generates it if absent
+func (f *fileStore) getEncryptionKey(metadata *fileMetadata, keySize int) ([]byte, error) {
+	if f.passphrase != "" {
+		return f.deriveEncryptionKey(metadata, keySize)
+	}
+	if metadata.KeyDerivation != "" {
+		return nil, fmt.Errorf("%w: %s", ErrPassphraseRequired, f.filePath)
+	}
+
 	// Try retrieving the key as a string from the keyring
 	keyStr, err := keyring.Get(f.namespaceVersionURN, f.key)
 	if errors.Is(err, keyring.ErrNotFound) {
 		// Generate a new key if not found
-		key := make([]byte, aes256KeyLength)
+		key := make([]byte, keySize)
 		if _, err := rand.Read(key); err != nil {
 			return nil, err
 		}
@@ -181,61 +269,74 @@ func (f *fileStore) getEncryptionKey() ([]byte, error) {
 	return []byte(keyStr), nil
 }
 
-// encryptData encrypts data using AES-GCM
-func encryptData(key, data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	aesGCM, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-	nonce := make([]byte, aesGCM.NonceSize())
-	if _, err := rand.Read(nonce); err != nil {
-		return nil, err
+// deriveEncryptionKey derives the key from the passphrase and the salt recorded in the metadata,
+// generating a new salt for files written without one
+func (f *fileStore) deriveEncryptionKey(metadata *fileMetadata, keySize int) ([]byte, error) {
+	switch metadata.KeyDerivation {
+	case keyDerivationArgon2id:
+		salt, err := base64.StdEncoding.DecodeString(metadata.KeySalt)
+		if err != nil || len(salt) == 0 {
+			return nil, errors.Join(ErrStoredValueInvalid, ErrEncryptedDataInvalid)
+		}
+		f.keySalt = salt
+	case "":
+		if metadata.EncryptionAlg != "" {
+			// existing value is keyed by the keyring, not a passphrase
+			return nil, errors.Join(ErrStoredValueInvalid, fmt.Errorf("%s is not passphrase encrypted", f.filePath))
+		}
+		f.keySalt = make([]byte, keySaltLength)
+		if _, err := rand.Read(f.keySalt); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: key derivation %s", ErrCipherUnsupported, metadata.KeyDerivation)
 	}
-	// Encrypt the data with a separate destination buffer
-	ciphertext := aesGCM.Seal(nil, nonce, data, nil)
-	// Prepend the nonce to the ciphertext
-	result := make([]byte, len(nonce)+len(ciphertext))
-	copy(result, nonce)
-	copy(result[len(nonce):], ciphertext)
-	return result, nil
+	return DeriveKey(f.passphrase, f.keySalt, keySize), nil
 }
 
-// decryptData decrypts data using AES-GCM
-func decryptData(key, encryptedData []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	aesGCM, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-	nonceSize := aesGCM.NonceSize()
-	if len(encryptedData) < nonceSize {
-		return nil, errors.Join(ErrStoredValueInvalid, ErrEncryptedDataInvalid)
+// DeriveKey stretches a passphrase into a key of keySize bytes using argon2id.
+func DeriveKey(passphrase string, salt []byte, keySize int) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, 1, 64*1024, 4, uint32(keySize))
+}
+
+// storedMetadata loads the metadata of the existing file. Files without metadata are new or
+// predate per-file algorithms, and resolve to an empty metadata.
+func (f *fileStore) storedMetadata() (*fileMetadata, error) {
+	metadata, err := f.LoadMetadata()
+	if errors.Is(err, os.ErrNotExist) {
+		return &fileMetadata{}, nil
 	}
-	nonce, ciphertext := encryptedData[:nonceSize], encryptedData[nonceSize:]
-	return aesGCM.Open(nil, nonce, ciphertext, nil)
+	return metadata, err
 }
 
 // SaveMetadata writes unencrypted metadata to a .nfo file
 func (f *fileStore) SaveMetadata(profileName string) error {
+	now := time.Now().Format(time.RFC3339)
 	metadata := fileMetadata{
 		ProfileName:   profileName,
-		CreatedAt:     time.Now().Format(time.RFC3339),
-		EncryptionAlg: "AES-256-GCM",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		EncryptionAlg: f.cipher.Name(),
 		Version:       f.namespaceVersionURN,
 	}
+	if existing, err := f.LoadMetadata(); err == nil {
+		if existing.CreatedAt != "" {
+			metadata.CreatedAt = existing.CreatedAt
+		}
+		if existing.EncryptionAlg != "" && existing.EncryptionAlg != metadata.EncryptionAlg {
+			metadata.PreviousEncryptionAlg = existing.EncryptionAlg
+		}
+	}
+	if f.passphrase != "" {
+		metadata.KeyDerivation = keyDerivationArgon2id
+		metadata.KeySalt = base64.StdEncoding.EncodeToString(f.keySalt)
+	}
 	data, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		return err
 	}
 	metadataFilePath := strings.TrimSuffix(f.filePath, filepath.Ext(f.filePath)) + ".nfo"
-	return os.WriteFile(metadataFilePath, data, ownerPermissionsRW)
+	return wrapFileError(writeFileAtomic(metadataFilePath, data))
 }
 
 // LoadMetadata loads and parses metadata from a .nfo file
@@ -243,11 +344,33 @@ func (f *fileStore) LoadMetadata() (*fileMetadata, error) {
 	metadataFilePath := strings.TrimSuffix(f.filePath, filepath.Ext(f.filePath)) + ".nfo"
 	data, err := os.ReadFile(metadataFilePath)
 	if err != nil {
-		return nil, err
+		return nil, wrapFileError(err)
 	}
 	var metadata fileMetadata
 	if err := json.Unmarshal(data, &metadata); err != nil {
-		return nil, err
+		return nil, errors.Join(ErrStoredValueInvalid, err)
 	}
 	return &metadata, nil
 }
+
+// writeFileAtomic replaces the file at path with data, owner read/write only, by renaming a temp
+// file over it so an interrupted write or a concurrent reader never observes a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(ownerPermissionsRW); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -2,7 +2,17 @@ package store
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
 
 	"github.com/zalando/go-keyring"
 )
@@ -10,15 +20,58 @@ import (
 type keyringStore struct {
 	namespace string
 	key       string
+	// compression gzip compresses values that need chunking
+	compression bool
 }
 
-var NewKeyringStore NewStoreInterface = func(serviceNamespace, key string, _ ...DriverOpt) (StoreInterface, error) {
+// Values longer than keyringChunkSize are split across several keyring entries. The size stays below
+// the Windows Credential Manager limit (2560 bytes) and the macOS Keychain command limit (4096 bytes).
+const (
+	keyringChunkSize = 2048
+	keyringMaxChunks = 64
+
+	// keyringManifestPrefix marks an entry as a manifest of chunks. It cannot start a JSON document.
+	keyringManifestPrefix  = "goosprofiles-chunked:"
+	keyringCompressionGzip = "gzip"
+
+	// keyringChunkSeparator joins chunk entry keys. It is outside the characters allowed in store
+	// keys, so chunk entries never collide with the entry of another key.
+	keyringChunkSeparator  = ":"
+	keyringGenerationBytes = 8
+)
+
+// keyringManifest is stored under the entry key when a value is chunked. Each write stores its
+// chunks under a new generation, so the previous value stays readable until the manifest is replaced.
+type keyringManifest struct {
+	Chunks      int    `json:"chunks"`
+	Generation  string `json:"generation"`
+	SHA256      string `json:"sha256"`
+	Compression string `json:"compression,omitempty"`
+}
+
+// WithKeyringCompression compresses values too large for a single keyring entry before chunking them.
+func WithKeyringCompression() DriverOpt {
+	return func(o *DriverOptions) error {
+		o.KeyringCompression = true
+		return nil
+	}
+}
+
+var NewKeyringStore NewStoreInterface = func(serviceNamespace, key string, driverOpts ...DriverOpt) (StoreInterface, error) {
 	if err := ValidateNamespaceKey(serviceNamespace, key); err != nil {
 		return nil, err
 	}
+
+	// Apply any driver options
+	opts, err := ApplyDriverOpts(driverOpts)
+	if err != nil {
+		return nil, err
+	}
+
 	return &keyringStore{
-		namespace: serviceNamespace,
-		key:       key,
+		namespace:   serviceNamespace,
+		key:         key,
+		compression: opts.KeyringCompression,
 	}, nil
 }
 
@@ -30,9 +83,36 @@ func (k *keyringStore) Exists() bool {
 func (k *keyringStore) Get() ([]byte, error) {
 	s, err := keyring.Get(k.namespace, k.key)
 	if err != nil {
-		return nil, err
+		return nil, wrapKeyringError(err)
+	}
+	manifest, chunked, err := parseKeyringManifest(s)
+	if err != nil || !chunked {
+		return []byte(s), err
+	}
+
+	var encoded strings.Builder
+	for i := range manifest.Chunks {
+		chunk, err := keyring.Get(k.namespace, k.chunkKey(manifest.Generation, i))
+		if err != nil {
+			return nil, errors.Join(ErrStoredValueInvalid, fmt.Errorf("missing chunk %d of %d: %w", i+1, manifest.Chunks, err))
+		}
+		encoded.WriteString(chunk)
 	}
-	return []byte(s), err
+	data, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, errors.Join(ErrStoredValueInvalid, err)
+	}
+	if manifest.Compression == keyringCompressionGzip {
+		if data, err = gunzip(data); err != nil {
+			return nil, errors.Join(ErrStoredValueInvalid, err)
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != manifest.SHA256 {
+		return nil, errors.Join(ErrStoredValueInvalid, errors.New("chunked value failed integrity check"))
+	}
+	return data, nil
 }
 
 func (k *keyringStore) Set(value interface{}) error {
@@ -40,9 +120,134 @@ func (k *keyringStore) Set(value interface{}) error {
 	if err := json.NewEncoder(&b).Encode(value); err != nil {
 		return err
 	}
-	return keyring.Set(k.namespace, k.key, b.String())
+
+	stale, _ := k.storedManifest()
+	if b.Len() <= keyringChunkSize {
+		if err := k.setEntry(k.key, b.String()); err != nil {
+			return err
+		}
+		return k.deleteChunks(stale)
+	}
+
+	generation := make([]byte, keyringGenerationBytes)
+	if _, err := rand.Read(generation); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b.Bytes())
+	manifest := keyringManifest{Generation: hex.EncodeToString(generation), SHA256: hex.EncodeToString(sum[:])}
+	data := b.Bytes()
+	if k.compression {
+		compressed, err := gzipBytes(data)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		manifest.Compression = keyringCompressionGzip
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	manifest.Chunks = (len(encoded) + keyringChunkSize - 1) / keyringChunkSize
+	if manifest.Chunks > keyringMaxChunks {
+		return fmt.Errorf("%w: %d bytes exceeds %d chunks of %d bytes", ErrValueTooLarge, b.Len(), keyringMaxChunks, keyringChunkSize)
+	}
+
+	for i := range manifest.Chunks {
+		chunk := encoded[i*keyringChunkSize : min((i+1)*keyringChunkSize, len(encoded))]
+		if err := k.setEntry(k.chunkKey(manifest.Generation, i), chunk); err != nil {
+			return err
+		}
+	}
+	m, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	// The manifest is written last so readers never see it before all of its chunks, and the
+	// chunks of the previous value are only removed once it is replaced
+	if err := k.setEntry(k.key, keyringManifestPrefix+string(m)); err != nil {
+		return err
+	}
+	return k.deleteChunks(stale)
 }
 
 func (k *keyringStore) Delete() error {
-	return keyring.Delete(k.namespace, k.key)
+	stale, _ := k.storedManifest()
+	if err := k.deleteChunks(stale); err != nil {
+		return err
+	}
+	return wrapKeyringError(keyring.Delete(k.namespace, k.key))
+}
+
+func (k *keyringStore) setEntry(key, value string) error {
+	err := keyring.Set(k.namespace, key, value)
+	if errors.Is(err, keyring.ErrSetDataTooBig) {
+		return errors.Join(ErrValueTooLarge, err)
+	}
+	return err
+}
+
+// storedManifest returns the currently stored manifest, if the stored value is chunked
+func (k *keyringStore) storedManifest() (keyringManifest, bool) {
+	s, err := keyring.Get(k.namespace, k.key)
+	if err != nil {
+		return keyringManifest{}, false
+	}
+	manifest, chunked, err := parseKeyringManifest(s)
+	if err != nil || !chunked {
+		return keyringManifest{}, false
+	}
+	return manifest, true
+}
+
+// deleteChunks removes the chunk entries referenced by a manifest
+func (k *keyringStore) deleteChunks(manifest keyringManifest) error {
+	for i := range manifest.Chunks {
+		if err := keyring.Delete(k.namespace, k.chunkKey(manifest.Generation, i)); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkKey returns the entry key of chunk i of a generation, e.g. "profile-x:chunk:<generation>:0"
+func (k *keyringStore) chunkKey(generation string, i int) string {
+	return strings.Join([]string{k.key, "chunk", generation, strconv.Itoa(i)}, keyringChunkSeparator)
+}
+
+func parseKeyringManifest(s string) (keyringManifest, bool, error) {
+	var manifest keyringManifest
+	m, chunked := strings.CutPrefix(s, keyringManifestPrefix)
+	if !chunked {
+		return manifest, false, nil
+	}
+	if err := json.Unmarshal([]byte(m), &manifest); err != nil {
+		return manifest, true, errors.Join(ErrStoredValueInvalid, err)
+	}
+	if manifest.Generation == "" {
+		return manifest, true, errors.Join(ErrStoredValueInvalid, errors.New("missing chunk generation"))
+	}
+	if manifest.Chunks < 1 || manifest.Chunks > keyringMaxChunks {
+		return manifest, true, errors.Join(ErrStoredValueInvalid, fmt.Errorf("invalid chunk count %d", manifest.Chunks))
+	}
+	return manifest, true, nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
 }
@@ -2,12 +2,14 @@ package store
 
 import (
 	"encoding/json"
+	"sync"
 )
 
 type memoryStore struct {
 	namespace string
 	key       string
 
+	mu     *sync.Mutex
 	memory *map[string]interface{}
 }
 
@@ -22,37 +24,78 @@ var NewMemoryStore NewStoreInterface = func(serviceNamespace, key string, _ ...D
 	return &memoryStore{
 		namespace: serviceNamespace,
 		key:       key,
+		mu:        &sync.Mutex{},
 		memory:    &memory,
 	}, nil
 }
 
+// NewSharedMemoryStore returns a constructor of in-memory stores backed by one map, so a value set
+// through a store is visible to every store constructed for the same namespace and key. Values are
+// kept as JSON, so later changes to a stored struct do not leak into the store.
+func NewSharedMemoryStore() NewStoreInterface {
+	var mu sync.Mutex
+	namespaces := make(map[string]*map[string]interface{})
+	return func(serviceNamespace, key string, _ ...DriverOpt) (StoreInterface, error) {
+		if err := ValidateNamespaceKey(serviceNamespace, key); err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		memory, ok := namespaces[serviceNamespace]
+		if !ok {
+			m := make(map[string]interface{})
+			memory = &m
+			namespaces[serviceNamespace] = memory
+		}
+		return &memoryStore{
+			namespace: serviceNamespace,
+			key:       key,
+			mu:        &mu,
+			memory:    memory,
+		}, nil
+	}
+}
+
 func (k *memoryStore) Exists() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 	m := *k.memory
 	_, ok := m[k.key]
 	return ok
 }
 
 func (k *memoryStore) Get() ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 	m := *k.memory
 	v, ok := m[k.key]
 	if !ok {
-		return nil, nil
+		return nil, ErrNotFound
 	}
 
 	return json.Marshal(v)
 }
 
 func (k *memoryStore) Set(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
 	m := *k.memory
-	m[k.key] = value
-	// maybe write back to k.memory
-	// k.memory = &m
+	m[k.key] = json.RawMessage(data)
 	return nil
 }
 
 func (k *memoryStore) Delete() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 	m := *k.memory
+	if _, ok := m[k.key]; !ok {
+		return ErrNotFound
+	}
 	delete(m, k.key)
-	// maybe write back to k.memory
 	return nil
 }
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/platform"
+)
+
+// plainFileStore persists values as unencrypted, pretty-printed JSON so non-secret
+// configuration can be inspected, hand-edited, diffed and version-controlled.
+type plainFileStore struct {
+	namespace string
+	key       string
+	filePath  string
+}
+
+const plainFileExt = ".json"
+
+// NewPlainFileStore is the constructor function for plainFileStore. Files are written to the directory
+// set by WithStoreDirectory, or the user-level platform config directory for the namespace, using the
+// same URN-based namespace/key file naming as the encrypted fileStore.
+var NewPlainFileStore NewStoreInterface = func(serviceNamespace, key string, driverOpts ...DriverOpt) (StoreInterface, error) {
+	if err := ValidateNamespaceKey(serviceNamespace, key); err != nil {
+		return nil, err
+	}
+
+	// Apply any driver options
+	opts, err := ApplyDriverOpts(driverOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPlainFileStore(serviceNamespace, key, opts.StoreDirectory)
+}
+
+// NewPlainFileStoreInDirectory returns a constructor for plainFileStore bound to dir, independent of
+// WithStoreDirectory, e.g. to read a second set of profiles alongside the configured store.
+func NewPlainFileStoreInDirectory(dir string) NewStoreInterface {
+	return func(serviceNamespace, key string, _ ...DriverOpt) (StoreInterface, error) {
+		if err := ValidateNamespaceKey(serviceNamespace, key); err != nil {
+			return nil, err
+		}
+		return newPlainFileStore(serviceNamespace, key, dir)
+	}
+}
+
+func newPlainFileStore(serviceNamespace, key, baseDir string) (StoreInterface, error) {
+	if baseDir == "" {
+		plat, err := platform.NewPlatform("", serviceNamespace, runtime.GOOS)
+		if err != nil {
+			return nil, errors.Join(ErrStoreDriverSetup, err)
+		}
+		baseDir = plat.UserAppConfigDirectory()
+	}
+
+	if err := os.MkdirAll(baseDir, ownerPermissionsRWX); err != nil {
+		return nil, errors.Join(ErrStoreDriverSetup, fmt.Errorf("failed to create profiles directory %s: %w", baseDir, err))
+	}
+
+	urn := BuildNamespaceURN(serviceNamespace, version1)
+	fileName := fmt.Sprintf("%s.%s", urn, key)
+	return &plainFileStore{
+		namespace: serviceNamespace,
+		key:       key,
+		filePath:  filepath.Join(baseDir, fileName+plainFileExt),
+	}, nil
+}
+
+// FilePath returns the path of the JSON file
+func (f *plainFileStore) FilePath() string {
+	return f.filePath
+}
+
+// Exists checks if the JSON file exists
+func (f *plainFileStore) Exists() bool {
+	_, err := os.Stat(f.filePath)
+	return err == nil
+}
+
+// Get reads the JSON file
+func (f *plainFileStore) Get() ([]byte, error) {
+	data, err := os.ReadFile(f.filePath)
+	return data, wrapFileError(err)
+}
+
+// Set writes the value as indented JSON, replacing the file atomically so a
+// concurrent reader or a hand-editor never observes a partial write
+func (f *plainFileStore) Set(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if err := writeFileAtomic(f.filePath, data); err != nil {
+		return fmt.Errorf("failed to write profile to %s: %w", f.filePath, wrapFileError(err))
+	}
+	return nil
+}
+
+// Delete removes the JSON file from disk
+func (f *plainFileStore) Delete() error {
+	return wrapFileError(os.Remove(f.filePath))
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+const (
+	// TagName is the struct tag read by the library, e.g. `osprofiles:"secret"`.
+	TagName = "osprofiles"
+	// TagSecret marks a field as secret so it is kept out of non-secret storage and output.
+	TagSecret = "secret"
+)
+
+// splitStore persists the secret top-level fields of a value in one store and the
+// remaining fields in another, re-joining them on Get.
+type splitStore struct {
+	public StoreInterface
+	secret StoreInterface
+}
+
+// NewSplitStore returns a store constructor that keeps fields tagged `osprofiles:"secret"` in the
+// secret store (e.g. NewKeyringStore) and all other fields in the public store (e.g. NewPlainFileStore).
+// Only top-level fields of the stored struct are considered.
+func NewSplitStore(newPublicStore, newSecretStore NewStoreInterface) NewStoreInterface {
+	return func(serviceNamespace, key string, driverOpts ...DriverOpt) (StoreInterface, error) {
+		public, err := newPublicStore(serviceNamespace, key, driverOpts...)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := newSecretStore(serviceNamespace, key, driverOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return &splitStore{public: public, secret: secret}, nil
+	}
+}
+
+// FilePath returns the path of the public store's file, if it is file-backed
+func (s *splitStore) FilePath() string {
+	if public, ok := s.public.(FileBackedStore); ok {
+		return public.FilePath()
+	}
+	return ""
+}
+
+func (s *splitStore) Exists() bool {
+	return s.public.Exists()
+}
+
+func (s *splitStore) Get() ([]byte, error) {
+	data, err := s.public.Get()
+	if err != nil {
+		return nil, err
+	}
+	// Only a missing secret entry means there are no secrets; any other error, e.g. an
+	// unavailable keyring, must not yield the value without its secret fields
+	secretData, err := s.secret.Get()
+	if errors.Is(err, ErrNotFound) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fields, secretFields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Join(ErrStoredValueInvalid, err)
+	}
+	if err := json.Unmarshal(secretData, &secretFields); err != nil {
+		return nil, errors.Join(ErrStoredValueInvalid, err)
+	}
+	if fields == nil {
+		fields = make(map[string]json.RawMessage, len(secretFields))
+	}
+	for name, v := range secretFields {
+		fields[name] = v
+	}
+	return json.Marshal(fields)
+}
+
+func (s *splitStore) Set(value interface{}) error {
+	secretNames := SecretFields(value)
+	if len(secretNames) == 0 {
+		if err := s.deleteSecret(); err != nil {
+			return err
+		}
+		return s.public.Set(value)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	secretFields := make(map[string]json.RawMessage, len(secretNames))
+	for _, name := range secretNames {
+		if v, ok := fields[name]; ok {
+			secretFields[name] = v
+			delete(fields, name)
+		}
+	}
+
+	// Write secrets first so a failure never leaves a public copy without its secrets
+	if len(secretFields) == 0 {
+		if err := s.deleteSecret(); err != nil {
+			return err
+		}
+	} else if err := s.secret.Set(secretFields); err != nil {
+		return err
+	}
+	return s.public.Set(fields)
+}
+
+func (s *splitStore) Delete() error {
+	if err := s.deleteSecret(); err != nil {
+		return err
+	}
+	return s.public.Delete()
+}
+
+func (s *splitStore) deleteSecret() error {
+	if err := s.secret.Delete(); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// PartialValue is a subset of the top-level fields of a struct of Type, e.g. only the fields of
+// a profile that differ from the profile it inherits from. It is stored as a JSON object while
+// the struct tags of Type still apply to its fields.
+type PartialValue struct {
+	Fields map[string]json.RawMessage
+	Type   reflect.Type
+}
+
+func (v PartialValue) MarshalJSON() ([]byte, error) {
+	if v.Fields == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(v.Fields)
+}
+
+// SecretFields returns the JSON names of the top-level fields of value tagged `osprofiles:"secret"`.
+func SecretFields(value interface{}) []string {
+	t := reflect.TypeOf(value)
+	if partial, ok := value.(PartialValue); ok {
+		t = partial.Type
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || !IsSecretField(field) {
+			continue
+		}
+		names = append(names, JSONFieldName(field))
+	}
+	return names
+}
+
+// IsSecretField reports whether the struct field is tagged `osprofiles:"secret"`.
+func IsSecretField(field reflect.StructField) bool {
+	return hasTagOption(field.Tag.Get(TagName), TagSecret)
+}
+
+// JSONFieldName returns the name encoding/json uses for the struct field.
+func JSONFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func hasTagOption(tag, option string) bool {
+	for _, o := range strings.Split(tag, ",") {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
 )
 
 func Test_ValidateNamespaceKey(t *testing.T) {
@@ -103,6 +106,26 @@ type mockStoredValue struct {
 	TestValue string `json:"test_value"`
 }
 
+func Test_ApplyDriverOpts(t *testing.T) {
+	opts, err := ApplyDriverOpts(nil)
+	require.NoError(t, err)
+	assert.Equal(t, CipherNameAES256GCM, opts.Cipher.Name())
+
+	// custom options set the settings read by the drivers
+	withCustomDirectory := func(o *DriverOptions) error {
+		o.StoreDirectory = "custom"
+		return nil
+	}
+	opts, err = ApplyDriverOpts([]DriverOpt{withCustomDirectory, WithKeyringCompression()})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", opts.StoreDirectory)
+	assert.True(t, opts.KeyringCompression)
+
+	_, err = ApplyDriverOpts([]DriverOpt{WithPassphrase("")})
+	require.ErrorIs(t, err, ErrStoreDriverSetup)
+	require.ErrorIs(t, err, ErrPassphraseRequired)
+}
+
 func Test_NewMemoryStore(t *testing.T) {
 	testNS := "test_namespace"
 	testKey := "profile"
@@ -133,6 +156,33 @@ func Test_NewMemoryStore(t *testing.T) {
 	assert.Equal(t, value.TestValue, storedValue.TestValue)
 }
 
+func Test_NewSharedMemoryStore(t *testing.T) {
+	newStore := NewSharedMemoryStore()
+	store, err := newStore("test_namespace", "profile")
+	require.NoError(t, err)
+
+	value := mockStoredValue{Name: "test_shared_memory"}
+	require.NoError(t, store.Set(&value))
+	value.TestValue = "changed after set"
+
+	same, err := newStore("test_namespace", "profile")
+	require.NoError(t, err)
+	data, err := same.Get()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "test_shared_memory", "test_value": ""}`, string(data))
+
+	other, err := newStore("other_namespace", "profile")
+	require.NoError(t, err)
+	require.False(t, other.Exists())
+
+	separate, err := NewSharedMemoryStore()("test_namespace", "profile")
+	require.NoError(t, err)
+	require.False(t, separate.Exists())
+
+	require.NoError(t, same.Delete())
+	require.False(t, store.Exists())
+}
+
 func Test_NewFileSystemStore_DirectoryProvided(t *testing.T) {
 	testNS := "test_namespace"
 	testKey := "profile"
@@ -200,6 +250,34 @@ func Test_NewFileSystemStore_DirectoryProvided(t *testing.T) {
 
 	assert.Equal(t, value.Name, storedValue.Name)
 	assert.Equal(t, value.TestValue, storedValue.TestValue)
+
+	// deleting removes the files and the encryption key kept in the keyring
+	require.NoError(t, store.Delete())
+	require.False(t, store.Exists())
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+	_, err = keyring.Get(BuildNamespaceURN(testNS, version1), testKey)
+	require.ErrorIs(t, err, keyring.ErrNotFound)
+}
+
+func Test_NewFileSystemStore_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := NewFileStore("test_namespace", "profile-"+strconv.Itoa(i), WithStoreDirectory(dir))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// the permission probes are cleaned up
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
 }
 
 func Test_NewKeyringStore(t *testing.T) {
@@ -242,3 +320,320 @@ func Test_NewKeyringStore(t *testing.T) {
 	assert.Equal(t, value.Name, storedValue.Name)
 	assert.Equal(t, value.TestValue, storedValue.TestValue)
 }
+
+func Test_Ciphers(t *testing.T) {
+	for _, c := range []Cipher{CipherAES256GCM, CipherXChaCha20Poly1305} {
+		t.Run(c.Name(), func(t *testing.T) {
+			key := make([]byte, c.KeySize())
+			plaintext := []byte(`{"name":"cipher_test"}`)
+
+			sealed, err := c.Encrypt(key, plaintext)
+			require.NoError(t, err)
+			assert.NotContains(t, string(sealed), "cipher_test")
+
+			opened, err := c.Decrypt(key, sealed)
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, opened)
+
+			_, err = c.Decrypt(key, sealed[:2])
+			require.ErrorIs(t, err, ErrEncryptedDataInvalid)
+
+			resolved, err := lookupCipher(c.Name())
+			require.NoError(t, err)
+			assert.Equal(t, c.Name(), resolved.Name())
+		})
+	}
+
+	resolved, err := lookupCipher("")
+	require.NoError(t, err)
+	assert.Equal(t, CipherNameAES256GCM, resolved.Name())
+
+	_, err = lookupCipher("ROT13")
+	require.ErrorIs(t, err, ErrCipherUnsupported)
+}
+
+func Test_NewPlainFileStore(t *testing.T) {
+	testNS := "test_namespace"
+	testKey := "profile"
+
+	dir := t.TempDir()
+	store, err := NewPlainFileStore(testNS, testKey, WithStoreDirectory(dir))
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	require.False(t, store.Exists())
+
+	value := mockStoredValue{
+		Name:      "plain_store_test",
+		TestValue: "plain_file_stored",
+	}
+	err = store.Set(value)
+	require.NoError(t, err)
+	require.True(t, store.Exists())
+
+	// ensure exactly one readable file was written to the temp dir
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, BuildNamespaceURN(testNS, version1)+"."+testKey+".json", files[0].Name())
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "\n  \"test_value\": \"plain_file_stored\"")
+
+	data, err = store.Get()
+	require.NoError(t, err)
+
+	var storedValue *mockStoredValue
+	err = json.Unmarshal(data, &storedValue)
+	require.NoError(t, err)
+	assert.Equal(t, value, *storedValue)
+
+	require.NoError(t, store.Delete())
+	require.False(t, store.Exists())
+}
+
+type mockSecretValue struct {
+	Name   string `json:"name"`
+	Token  string `json:"token" osprofiles:"secret"`
+	Secret string `osprofiles:"secret"`
+}
+
+func Test_NewSplitStore(t *testing.T) {
+	var public, secret StoreInterface
+	newPublic := func(ns, key string, opts ...DriverOpt) (StoreInterface, error) {
+		var err error
+		public, err = NewMemoryStore(ns, key, opts...)
+		return public, err
+	}
+	newSecret := func(ns, key string, opts ...DriverOpt) (StoreInterface, error) {
+		var err error
+		secret, err = NewMemoryStore(ns, key, opts...)
+		return secret, err
+	}
+
+	store, err := NewSplitStore(newPublic, newSecret)("test_namespace", "profile")
+	require.NoError(t, err)
+	require.False(t, store.Exists())
+
+	value := &mockSecretValue{Name: "split", Token: "t0ken", Secret: "s3cret"}
+	require.NoError(t, store.Set(value))
+	require.True(t, store.Exists())
+
+	publicData, err := public.Get()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"split"}`, string(publicData))
+
+	secretData, err := secret.Get()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"token":"t0ken","Secret":"s3cret"}`, string(secretData))
+
+	data, err := store.Get()
+	require.NoError(t, err)
+	var storedValue mockSecretValue
+	require.NoError(t, json.Unmarshal(data, &storedValue))
+	assert.Equal(t, *value, storedValue)
+
+	// values without secret fields leave no secret entry behind
+	require.NoError(t, store.Set(mockStoredValue{Name: "plain"}))
+	require.False(t, secret.Exists())
+
+	require.NoError(t, store.Delete())
+	require.False(t, store.Exists())
+}
+
+// unavailableStore fails every operation, like a keyring without a reachable secret service
+type unavailableStore struct {
+	StoreInterface
+}
+
+var errStoreUnavailable = errors.New("store unavailable")
+
+func (unavailableStore) Exists() bool                { return false }
+func (unavailableStore) Get() ([]byte, error)        { return nil, errStoreUnavailable }
+func (unavailableStore) Set(value interface{}) error { return errStoreUnavailable }
+func (unavailableStore) Delete() error               { return errStoreUnavailable }
+
+func Test_NewSplitStore_SecretStoreUnavailable(t *testing.T) {
+	public, err := NewMemoryStore("test_namespace", "profile")
+	require.NoError(t, err)
+	require.NoError(t, public.Set(map[string]string{"name": "split"}))
+
+	newPublic := func(string, string, ...DriverOpt) (StoreInterface, error) { return public, nil }
+	newSecret := func(string, string, ...DriverOpt) (StoreInterface, error) { return unavailableStore{}, nil }
+	store, err := NewSplitStore(newPublic, newSecret)("test_namespace", "profile")
+	require.NoError(t, err)
+
+	// the value is not returned without its secret fields
+	_, err = store.Get()
+	require.ErrorIs(t, err, errStoreUnavailable)
+
+	// secrets are not orphaned by removing the public value alone
+	require.ErrorIs(t, store.Delete(), errStoreUnavailable)
+	require.ErrorIs(t, store.Set(mockStoredValue{Name: "plain"}), errStoreUnavailable)
+	require.True(t, public.Exists())
+}
+
+func Test_NewKeyringStore_Chunked(t *testing.T) {
+	testNS := "test_namespace"
+	testKey := "profile-chunked"
+
+	store, err := NewKeyringStore(testNS, testKey, WithKeyringCompression())
+	require.NoError(t, err)
+
+	// larger than a single keyring entry
+	value := mockStoredValue{
+		Name:      "test_keyring_chunked",
+		TestValue: strings.Repeat("certificate-data", keyringChunkSize),
+	}
+	require.NoError(t, store.Set(value))
+	require.True(t, store.Exists())
+
+	data, err := store.Get()
+	require.NoError(t, err)
+	var storedValue *mockStoredValue
+	require.NoError(t, json.Unmarshal(data, &storedValue))
+	assert.Equal(t, value, *storedValue)
+
+	// chunk entries do not collide with keys that look like them
+	lookalike, err := NewKeyringStore(testNS, testKey+"-chunk-0")
+	require.NoError(t, err)
+	require.NoError(t, lookalike.Set(mockStoredValue{Name: "lookalike"}))
+	t.Cleanup(func() { _ = lookalike.Delete() })
+
+	// rewriting a chunked value moves it to a new generation and removes the previous one
+	previous, _ := store.(*keyringStore).storedManifest()
+	value.TestValue = strings.Repeat("renewed-certificate", keyringChunkSize)
+	require.NoError(t, store.Set(value))
+	current, _ := store.(*keyringStore).storedManifest()
+	require.NotEqual(t, previous.Generation, current.Generation)
+	_, err = keyring.Get(testNS, store.(*keyringStore).chunkKey(previous.Generation, 0))
+	require.ErrorIs(t, err, keyring.ErrNotFound)
+	data, err = store.Get()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &storedValue))
+	assert.Equal(t, value, *storedValue)
+
+	data, err = lookalike.Get()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "lookalike", "test_value": ""}`, string(data))
+
+	// shrinking the value removes the chunks
+	value.TestValue = "small"
+	require.NoError(t, store.Set(value))
+	_, chunked := store.(*keyringStore).storedManifest()
+	require.False(t, chunked)
+	_, err = keyring.Get(testNS, store.(*keyringStore).chunkKey(current.Generation, 0))
+	require.ErrorIs(t, err, keyring.ErrNotFound)
+	data, err = store.Get()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &storedValue))
+	assert.Equal(t, value, *storedValue)
+
+	// incompressible values beyond the chunk limit are rejected
+	store.(*keyringStore).compression = false
+	value.TestValue = strings.Repeat("x", keyringChunkSize*keyringMaxChunks)
+	require.ErrorIs(t, store.Set(value), ErrValueTooLarge)
+
+	require.NoError(t, store.Delete())
+	require.False(t, store.Exists())
+}
+
+func Test_NewFileSystemStore_Passphrase(t *testing.T) {
+	testNS := "test_namespace"
+	testKey := "profile"
+
+	dir := t.TempDir()
+	store, err := NewFileStore(testNS, testKey, WithStoreDirectory(dir), WithPassphrase("correct horse battery staple"))
+	require.NoError(t, err)
+
+	value := mockStoredValue{
+		Name:      "fs_passphrase_test",
+		TestValue: "passphrase_stored",
+	}
+	require.NoError(t, store.Set(value))
+
+	metadata, err := store.(*fileStore).LoadMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, keyDerivationArgon2id, metadata.KeyDerivation)
+	assert.NotEmpty(t, metadata.KeySalt)
+
+	data, err := store.Get()
+	require.NoError(t, err)
+	var storedValue *mockStoredValue
+	require.NoError(t, json.Unmarshal(data, &storedValue))
+	assert.Equal(t, value, *storedValue)
+
+	// a different passphrase cannot decrypt the value
+	wrong, err := NewFileStore(testNS, testKey, WithStoreDirectory(dir), WithPassphrase("wrong"))
+	require.NoError(t, err)
+	_, err = wrong.Get()
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// the value cannot be read without a passphrase, which is not kept for later stores
+	noPassphrase, err := NewFileStore(testNS, testKey, WithStoreDirectory(dir))
+	require.NoError(t, err)
+	_, err = noPassphrase.Get()
+	require.ErrorIs(t, err, ErrPassphraseRequired)
+}
+
+func Test_NewFileSystemStore_InterruptedCipherSwitch(t *testing.T) {
+	testNS := "test_namespace"
+	testKey := "profile"
+
+	dir := t.TempDir()
+	passphrase := WithPassphrase("correct horse battery staple")
+	store, err := NewFileStore(testNS, testKey, WithStoreDirectory(dir), passphrase)
+	require.NoError(t, err)
+	value := mockStoredValue{Name: "fs_cipher_switch", TestValue: "aes"}
+	require.NoError(t, store.Set(value))
+
+	// the metadata of the new cipher is written before the value, which is interrupted
+	switched, err := NewFileStore(testNS, testKey, WithStoreDirectory(dir), passphrase, WithCipher(CipherXChaCha20Poly1305))
+	require.NoError(t, err)
+	metadata, err := switched.(*fileStore).storedMetadata()
+	require.NoError(t, err)
+	_, err = switched.(*fileStore).getEncryptionKey(metadata, CipherXChaCha20Poly1305.KeySize())
+	require.NoError(t, err)
+	require.NoError(t, switched.(*fileStore).SaveMetadata(testKey))
+
+	data, err := switched.Get()
+	require.NoError(t, err)
+	var storedValue *mockStoredValue
+	require.NoError(t, json.Unmarshal(data, &storedValue))
+	assert.Equal(t, value, *storedValue)
+
+	// completing the write switches the value to the new cipher
+	value.TestValue = "xchacha"
+	require.NoError(t, switched.Set(value))
+	data, err = store.Get()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &storedValue))
+	assert.Equal(t, value, *storedValue)
+
+	// no temp files are left behind
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+}
+
+func Test_ErrNotFound(t *testing.T) {
+	testNS := "test_namespace"
+	testKey := "missing"
+
+	dir := t.TempDir()
+	stores := map[string]NewStoreInterface{
+		"memory":     NewMemoryStore,
+		"plain-file": NewPlainFileStore,
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := newStore(testNS, testKey, WithStoreDirectory(dir))
+			require.NoError(t, err)
+
+			_, err = store.Get()
+			require.ErrorIs(t, err, ErrNotFound)
+			require.ErrorIs(t, store.Delete(), ErrNotFound)
+		})
+	}
+}
@@ -3,9 +3,13 @@ package profiles
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"time"
 
 	"github.com/jrschumacher/go-osprofiles/internal/global"
 	"github.com/jrschumacher/go-osprofiles/pkg/store"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 type profileConfig struct {
@@ -13,6 +17,50 @@ type profileConfig struct {
 	driver     global.ProfileDriver
 
 	driverOpts []store.DriverOpt
+
+	// secretStore, when set, receives fields tagged `osprofiles:"secret"` instead of the driver
+	secretStore store.NewStoreInterface
+
+	// storeCandidates are probed in order at New and the first usable one configures the driver
+	storeCandidates []profileConfigVariadicFunc
+	selection       StoreSelection
+
+	// flagProfile, profileEnvVar and profilePinFile select the active profile ahead of the default profile
+	flagProfile    string
+	profileEnvVar  string
+	profilePinFile string
+
+	// envOverrides applies `env` struct tag overrides to loaded profiles
+	envOverrides bool
+
+	// systemProfilesDir holds read-only system-wide profiles layered under the user's profiles
+	systemProfilesDir string
+
+	// history limits the previous revisions kept of each profile
+	history HistoryRetention
+
+	// softDelete moves deleted profiles to the trash
+	softDelete bool
+
+	// watch configures how Watch detects changes
+	watch WatchOptions
+
+	// hooks run around profile operations
+	hooks []Hooks
+
+	// schema is the JSON Schema profiles are validated against, compiled at New
+	schema         []byte
+	compiledSchema *jsonschema.Schema
+}
+
+// StoreSelection reports which storage driver a Profiler selected when configured WithFallbackStores.
+type StoreSelection struct {
+	// Driver is the name of the selected driver (e.g. "keyring", "file", "in-memory").
+	Driver string
+	// Fallback is true when the primary driver was unavailable.
+	Fallback bool
+	// Err holds the probe failures of every driver skipped before the selected one.
+	Err error
 }
 
 // Profiler is the main interface for managing profiles
@@ -21,14 +69,20 @@ type Profiler struct {
 
 	globalStore         *global.GlobalStore
 	currentProfileStore *ProfileStore
+	systemProfiles      *systemProfiles
 }
 
 type (
 	profileConfigVariadicFunc func(profileConfig) profileConfig
 )
 
+// newMemoryStore backs the in-memory driver, keeping the profiles of each configuration name
+var newMemoryStore = store.NewSharedMemoryStore()
+
 // Variadic functions to set different storage drivers
 
+// WithInMemoryStore keeps profiles in memory for the lifetime of the process, shared by the
+// Profilers of the same configuration name, e.g. as the last of WithFallbackStores or in tests.
 func WithInMemoryStore() profileConfigVariadicFunc {
 	return func(c profileConfig) profileConfig {
 		c.driver = global.PROFILE_DRIVER_IN_MEMORY
@@ -51,6 +105,56 @@ func WithFileStore(storeDir string) profileConfigVariadicFunc {
 	}
 }
 
+// WithFileStorePassphrase derives the file store encryption key from a passphrase instead of
+// keeping a generated key in the OS keyring.
+func WithFileStorePassphrase(passphrase string) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.driverOpts = append(c.driverOpts, store.WithPassphrase(passphrase))
+		return c
+	}
+}
+
+// WithFallbackStores probes the primary store option at New and falls back to each of the fallback
+// options in order when it is unavailable (e.g. no Secret Service in an SSH session or container).
+// The selected driver is reported by GetStoreSelection.
+//
+// Example:
+//
+//	New("example_app", WithFallbackStores(WithKeyringStore(), WithFileStore(dir), WithInMemoryStore()))
+func WithFallbackStores(primary profileConfigVariadicFunc, fallbacks ...profileConfigVariadicFunc) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.storeCandidates = append([]profileConfigVariadicFunc{primary}, fallbacks...)
+		return c
+	}
+}
+
+// WithKeyringCompression gzip compresses keyring values that are too large for a single entry.
+func WithKeyringCompression() profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.driverOpts = append(c.driverOpts, store.WithKeyringCompression())
+		return c
+	}
+}
+
+// WithPlainFileStore stores profiles as unencrypted, human-readable JSON files. It is intended for
+// non-secret configuration only. An empty storeDir uses the platform user config directory.
+func WithPlainFileStore(storeDir string) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.driver = global.PROFILE_DRIVER_PLAIN_FILE
+		c.driverOpts = append(c.driverOpts, store.WithStoreDirectory(storeDir))
+		return c
+	}
+}
+
+// WithFileStoreCipher selects the cipher used by the file store driver for new writes.
+// Values written with another built-in cipher remain readable.
+func WithFileStoreCipher(cipher store.Cipher) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.driverOpts = append(c.driverOpts, store.WithCipher(cipher))
+		return c
+	}
+}
+
 func WithCustomStore(newCustomStore store.NewStoreInterface) profileConfigVariadicFunc {
 	return func(c profileConfig) profileConfig {
 		c.driver = global.PROFILE_DRIVER_CUSTOM
@@ -59,15 +163,43 @@ func WithCustomStore(newCustomStore store.NewStoreInterface) profileConfigVariad
 	}
 }
 
-// newStoreFactory returns a storage interface based on the configured driver
-func newStoreFactory(driver global.ProfileDriver) store.NewStoreInterface {
-	switch driver {
+// WithKeyringSecrets keeps profile fields tagged `osprofiles:"secret"` in the OS keyring while
+// the remaining fields are persisted by the configured driver (e.g. WithPlainFileStore).
+func WithKeyringSecrets() profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.secretStore = store.NewKeyringStore
+		return c
+	}
+}
+
+// newStoreFactory returns a storage interface based on the configured driver. The stores it
+// constructs are passed the driver options of the configuration.
+func newStoreFactory(config profileConfig) store.NewStoreInterface {
+	newStore := newDriverStoreFactory(config)
+	if newStore == nil {
+		return nil
+	}
+	if config.secretStore != nil {
+		newStore = store.NewSplitStore(newStore, config.secretStore)
+	}
+	if len(config.driverOpts) == 0 {
+		return newStore
+	}
+	return func(serviceNamespace, key string, driverOpts ...store.DriverOpt) (store.StoreInterface, error) {
+		return newStore(serviceNamespace, key, append(slices.Clip(config.driverOpts), driverOpts...)...)
+	}
+}
+
+func newDriverStoreFactory(config profileConfig) store.NewStoreInterface {
+	switch config.driver {
 	case global.PROFILE_DRIVER_KEYRING:
 		return store.NewKeyringStore
 	case global.PROFILE_DRIVER_IN_MEMORY:
-		return store.NewMemoryStore
+		return newMemoryStore
 	case global.PROFILE_DRIVER_FILE:
 		return store.NewFileStore
+	case global.PROFILE_DRIVER_PLAIN_FILE:
+		return store.NewPlainFileStore
 	case global.PROFILE_DRIVER_CUSTOM:
 		return store.NewCustomStore
 	default:
@@ -84,7 +216,16 @@ func buildProfileConfig(configName string, opts ...profileConfigVariadicFunc) (p
 		config = opt(config)
 	}
 
-	newStore := newStoreFactory(config.driver)
+	var err error
+	if config.compiledSchema, err = compileSchema(config.schema); err != nil {
+		return profileConfig{}, nil, err
+	}
+
+	if len(config.storeCandidates) > 0 {
+		return selectStoreCandidate(config)
+	}
+
+	newStore := newStoreFactory(config)
 	if newStore == nil {
 		return profileConfig{}, nil, ErrInvalidStoreDriver
 	}
@@ -92,6 +233,60 @@ func buildProfileConfig(configName string, opts ...profileConfigVariadicFunc) (p
 	return config, newStore, nil
 }
 
+// selectStoreCandidate returns the configuration of the first store candidate that passes a probe
+func selectStoreCandidate(base profileConfig) (profileConfig, store.NewStoreInterface, error) {
+	candidates := base.storeCandidates
+	base.storeCandidates = nil
+	base.driverOpts = slices.Clip(base.driverOpts)
+
+	var errs []error
+	for i, candidate := range candidates {
+		config := candidate(base)
+		newStore := newStoreFactory(config)
+		if newStore == nil {
+			errs = append(errs, fmt.Errorf("%s: %w", config.driver, ErrInvalidStoreDriver))
+			continue
+		}
+		if err := probeStore(config, newStore); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", config.driver, err))
+			continue
+		}
+
+		config.selection = StoreSelection{
+			Driver:   string(config.driver),
+			Fallback: i > 0,
+			Err:      errors.Join(errs...),
+		}
+		return config, newStore, nil
+	}
+
+	return profileConfig{}, nil, errors.Join(append([]error{ErrNoAvailableStore}, errs...)...)
+}
+
+// probeStore verifies the store is usable by writing and removing a probe entry. The secret store
+// is probed on its own, since the probe entry has no secret fields to route to it.
+func probeStore(config profileConfig, newStore store.NewStoreInterface) error {
+	if err := probeStoreEntry(config, newStore); err != nil {
+		return err
+	}
+	if config.secretStore != nil {
+		return probeStoreEntry(config, config.secretStore)
+	}
+	return nil
+}
+
+// probeStoreEntry writes and removes the probe entry through stores constructed by newStore
+func probeStoreEntry(config profileConfig, newStore store.NewStoreInterface) error {
+	s, err := newStore(config.configName, global.STORE_KEY_PROBE, config.driverOpts...)
+	if err != nil {
+		return err
+	}
+	if err := s.Set(global.PROFILES_VERSION_LATEST); err != nil {
+		return err
+	}
+	return s.Delete()
+}
+
 // New creates a new Profile with the specified configuration options.
 // The configName is required and must be unique to the application.
 func New(configName string, opts ...profileConfigVariadicFunc) (*Profiler, error) {
@@ -112,6 +307,12 @@ func New(configName string, opts ...profileConfigVariadicFunc) (*Profiler, error
 		return nil, err
 	}
 
+	// Load read-only system profiles
+	p.systemProfiles, err = loadSystemProfiles(configName, config.systemProfilesDir)
+	if err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
 
@@ -125,6 +326,15 @@ func HasGlobalStore(configName string, opts ...profileConfigVariadicFunc) (bool,
 	return global.HasGlobalStore(configName, newStore, config.driverOpts...)
 }
 
+// GetStoreSelection reports the storage driver in use by the profiler and, when configured
+// WithFallbackStores, whether the primary driver was skipped and why.
+func GetStoreSelection(p *Profiler) StoreSelection {
+	if p.config.selection.Driver == "" {
+		return StoreSelection{Driver: string(p.config.driver)}
+	}
+	return p.config.selection
+}
+
 // GetGlobalConfig returns the global configuration
 func GetGlobalConfig(p *Profiler) *global.GlobalStore {
 	return p.globalStore
@@ -132,34 +342,53 @@ func GetGlobalConfig(p *Profiler) *global.GlobalStore {
 
 // AddProfile adds a new profile to the current configuration
 func (p *Profiler) AddProfile(profile NamedProfile, setDefault bool) error {
+	event := HookEvent{Op: OpAddProfile, Profile: profile.GetName(), Value: profile}
+	if err := p.beforeHooks(event); err != nil {
+		return p.newProfileError(OpAddProfile, event.Profile, err)
+	}
+	if err := p.addProfile(profile, setDefault); err != nil {
+		return err
+	}
+	return p.newProfileError(OpAddProfile, event.Profile, p.afterHooks(event))
+}
+
+func (p *Profiler) addProfile(profile NamedProfile, setDefault bool) error {
 	var err error
 	profileName := profile.GetName()
 
 	if err := validateProfileName(profileName); err != nil {
-		return err
+		return p.newProfileError(OpAddProfile, profileName, err)
 	}
 
 	// Check if the profile already exists
 	if p.globalStore.ProfileExists(profileName) {
-		return ErrProfileNameConflict
+		return p.newProfileError(OpAddProfile, profileName, ErrProfileNameConflict)
+	}
+	if p.globalStore.InTrash(profileName) {
+		return p.newProfileError(OpAddProfile, profileName, fmt.Errorf("%w: a deleted profile with this name is in the trash", ErrProfileNameConflict))
+	}
+	locked := p.lockedDefaultProfile() != ""
+	if setDefault && locked {
+		return p.newProfileError(OpAddProfile, profileName, fmt.Errorf("%w: %w", ErrProfileReadOnly, ErrDefaultProfileLocked))
 	}
 
 	// Create profile store and save
-	p.currentProfileStore, err = NewProfileStore(p.config.configName, newStoreFactory(p.config.driver), profile)
+	p.currentProfileStore, err = NewProfileStore(p.config.configName, newStoreFactory(p.config), profile)
 	if err != nil {
-		return err
+		return p.newProfileError(OpAddProfile, profileName, err)
 	}
+	p.currentProfileStore.schema = p.config.compiledSchema
 	if err := p.currentProfileStore.Save(); err != nil {
-		return err
+		return p.newProfileError(OpAddProfile, profileName, err)
 	}
 
 	// Add profile to global configuration
 	if err := p.globalStore.AddProfile(profileName); err != nil {
-		return err
+		return p.newProfileError(OpAddProfile, profileName, err)
 	}
 
-	if setDefault || p.globalStore.GetDefaultProfile() == "" {
-		return p.globalStore.SetDefaultProfile(profileName)
+	if setDefault || (!locked && p.globalStore.GetDefaultProfile() == "") {
+		return p.newProfileError(OpAddProfile, profileName, p.globalStore.SetDefaultProfile(profileName))
 	}
 
 	return nil
@@ -175,136 +404,277 @@ func GetCurrentProfile(p *Profiler) (*ProfileStore, error) {
 
 // GetProfile returns the profile store for the specified profile name
 func GetProfile[T NamedProfile](p *Profiler, profileName string) (*ProfileStore, error) {
+	if IsSystemProfile(p, profileName) {
+		store, err := loadProfileStore[T](p.config.configName, p.systemProfiles.newStore, profileName, profileLoadOptions{
+			envOverrides: p.config.envOverrides,
+			readOnly:     true,
+			schema:       p.config.compiledSchema,
+		})
+		if err != nil {
+			return nil, p.newProfileError(OpGetProfile, profileName, err)
+		}
+		return store, nil
+	}
 	if !p.globalStore.ProfileExists(profileName) {
-		return nil, ErrMissingProfileName
+		return nil, p.newProfileError(OpGetProfile, profileName, ErrMissingProfileName)
+	}
+	parents, err := p.profileAncestors(profileName)
+	if err != nil {
+		return nil, p.newProfileError(OpGetProfile, profileName, err)
+	}
+	store, err := loadProfileStore[T](p.config.configName, newStoreFactory(p.config), profileName, profileLoadOptions{
+		envOverrides: p.config.envOverrides,
+		parents:      parents,
+		schema:       p.config.compiledSchema,
+	})
+	if err != nil {
+		return nil, p.newProfileError(OpGetProfile, profileName, err)
 	}
-	return LoadProfileStore[T](p.config.configName, newStoreFactory(p.config.driver), profileName)
+	return store, nil
 }
 
-// ListProfiles returns a list of all profile names
+// ListProfiles returns a list of all profile names, followed by any system profiles
 func ListProfiles(p *Profiler) []string {
+	if system := p.listSystemProfiles(); len(system) > 0 {
+		return append(slices.Clone(p.globalStore.ListProfiles()), system...)
+	}
 	return p.globalStore.ListProfiles()
 }
 
 // UseProfile sets the current profile to the specified profile name
 func UseProfile[T NamedProfile](p *Profiler, profileName string) (*ProfileStore, error) {
-	var err error
+	event := HookEvent{Op: OpUseProfile, Profile: profileName}
+	if err := p.beforeHooks(event); err != nil {
+		return nil, p.newProfileError(OpUseProfile, profileName, err)
+	}
+	profileStore, err := useProfile[T](p, profileName)
+	if err != nil {
+		return profileStore, err
+	}
+	event.Value = profileStore.Profile
+	return profileStore, p.newProfileError(OpUseProfile, profileName, p.afterHooks(event))
+}
 
-	// If current profile is already set to this, return it
-	if p.currentProfileStore != nil && p.currentProfileStore.Profile.GetName() == profileName {
+func useProfile[T NamedProfile](p *Profiler, profileName string) (*ProfileStore, error) {
+	// Set current profile, unless it is already set to this
+	if p.currentProfileStore == nil || p.currentProfileStore.Profile.GetName() != profileName {
+		var err error
+		p.currentProfileStore, err = GetProfile[T](p, profileName)
+		if err != nil {
+			return p.currentProfileStore, p.newProfileError(OpUseProfile, profileName, err)
+		}
+	}
+	// System profiles have no metadata in the user's global configuration
+	if IsSystemProfile(p, profileName) {
 		return p.currentProfileStore, nil
 	}
-
-	// Set current profile
-	p.currentProfileStore, err = GetProfile[T](p, profileName)
-	return p.currentProfileStore, err
+	return p.currentProfileStore, p.newProfileError(OpUseProfile, profileName, p.touchProfileInfo(profileName, false))
 }
 
-// UseDefaultProfile sets the current profile to the default profile
+// UseDefaultProfile sets the current profile to the active profile, see ResolveActiveProfile.
+// Without a profile flag or environment variable this is the stored default profile.
 func UseDefaultProfile[T NamedProfile](p *Profiler) (*ProfileStore, error) {
-	defaultProfile := p.globalStore.GetDefaultProfile()
-	if defaultProfile == "" {
-		return nil, ErrMissingDefaultProfile
+	active, err := ResolveActiveProfile(p)
+	if err != nil {
+		return nil, err
 	}
-	return UseProfile[T](p, defaultProfile)
+	return UseProfile[T](p, active.Name)
 }
 
 // UpdateProfile updates the current profile with new data
 func UpdateCurrentProfile(p *Profiler, profile NamedProfile) error {
+	event := HookEvent{Op: OpUpdateProfile, Profile: profile.GetName(), Value: profile}
+	if err := p.beforeHooks(event); err != nil {
+		return p.newProfileError(OpUpdateProfile, event.Profile, err)
+	}
+	if err := updateCurrentProfile(p, profile); err != nil {
+		return err
+	}
+	return p.newProfileError(OpUpdateProfile, event.Profile, p.afterHooks(event))
+}
+
+func updateCurrentProfile(p *Profiler, profile NamedProfile) error {
 	if p.currentProfileStore == nil {
-		return fmt.Errorf("error: store cannot be nil, %w", ErrInvalidStoreDriver)
+		return p.newProfileError(OpUpdateProfile, profile.GetName(), fmt.Errorf("error: store cannot be nil, %w", ErrInvalidStoreDriver))
 	}
 	if p.currentProfileStore.Profile == nil {
-		return fmt.Errorf("error: profile cannot be nil, %w", ErrMissingCurrentProfile)
+		return p.newProfileError(OpUpdateProfile, profile.GetName(), fmt.Errorf("error: profile cannot be nil, %w", ErrMissingCurrentProfile))
+	}
+	if p.currentProfileStore.readOnly {
+		return p.newProfileError(OpUpdateProfile, profile.GetName(), ErrProfileReadOnly)
+	}
+	// Keep the stored version in the profile's history before it is replaced
+	if previous := p.currentProfileStore.GetProfileName(); p.globalStore.ProfileExists(previous) {
+		if err := p.recordRevision(previous, p.currentProfileStore.store, reflect.TypeOf(profile)); err != nil {
+			return p.newProfileError(OpUpdateProfile, profile.GetName(), err)
+		}
 	}
 	// TODO: do we need to update the global store if the name is different?
 	p.currentProfileStore.Profile = profile
-	return p.currentProfileStore.Save()
+	if err := p.currentProfileStore.Save(); err != nil {
+		return p.newProfileError(OpUpdateProfile, profile.GetName(), err)
+	}
+	if p.globalStore.ProfileExists(profile.GetName()) {
+		return p.newProfileError(OpUpdateProfile, profile.GetName(), p.touchProfileInfo(profile.GetName(), true))
+	}
+	return nil
 }
 
 // SetDefaultProfile sets the a specified profile to the default profile
 func SetDefaultProfile(p *Profiler, profileName string) error {
-	if !p.globalStore.ProfileExists(profileName) {
-		return ErrMissingProfileName
+	event := HookEvent{Op: OpSetDefaultProfile, Profile: profileName}
+	if err := p.beforeHooks(event); err != nil {
+		return p.newProfileError(OpSetDefaultProfile, profileName, err)
+	}
+	if err := setDefaultProfile(p, profileName); err != nil {
+		return err
 	}
-	return p.globalStore.SetDefaultProfile(profileName)
+	return p.newProfileError(OpSetDefaultProfile, profileName, p.afterHooks(event))
+}
+
+func setDefaultProfile(p *Profiler, profileName string) error {
+	if p.lockedDefaultProfile() != "" {
+		return p.newProfileError(OpSetDefaultProfile, profileName, ErrDefaultProfileLocked)
+	}
+	if !p.profileExists(profileName) {
+		return p.newProfileError(OpSetDefaultProfile, profileName, ErrMissingProfileName)
+	}
+	return p.newProfileError(OpSetDefaultProfile, profileName, p.globalStore.SetDefaultProfile(profileName))
 }
 
 // DeleteProfile removes a profile from storage
 func DeleteProfile[T NamedProfile](p *Profiler, profileName string) error {
+	event := HookEvent{Op: OpDeleteProfile, Profile: profileName}
+	if err := p.beforeHooks(event); err != nil {
+		return p.newProfileError(OpDeleteProfile, profileName, err)
+	}
+	if err := deleteProfile[T](p, profileName); err != nil {
+		return err
+	}
+	return p.newProfileError(OpDeleteProfile, profileName, p.afterHooks(event))
+}
+
+func deleteProfile[T NamedProfile](p *Profiler, profileName string) error {
+	if IsSystemProfile(p, profileName) {
+		return p.newProfileError(OpDeleteProfile, profileName, ErrProfileReadOnly)
+	}
 	// Check if the profile exists
 	if !p.globalStore.ProfileExists(profileName) {
-		return ErrMissingProfileName
+		return p.newProfileError(OpDeleteProfile, profileName, ErrMissingProfileName)
+	}
+	// Check no other profile inherits from it
+	if children := ListProfileChildren(p, profileName); len(children) > 0 {
+		return p.newProfileError(OpDeleteProfile, profileName, fmt.Errorf("%w: %q", ErrProfileHasChildren, children))
+	}
+	// Move the profile to the trash when soft deleting
+	if p.config.softDelete {
+		if profileName == p.globalStore.GetDefaultProfile() {
+			return p.newProfileError(OpDeleteProfile, profileName, ErrCannotDeleteDefaultProfile)
+		}
+		return p.newProfileError(OpDeleteProfile, profileName, p.trashProfile(profileName))
 	}
 	// Retrieve the profile
-	profile, err := LoadProfileStore[T](p.config.configName, newStoreFactory(p.config.driver), profileName)
+	profile, err := LoadProfileStore[T](p.config.configName, newStoreFactory(p.config), profileName)
 	if err != nil {
-		return err
+		return p.newProfileError(OpDeleteProfile, profileName, err)
 	}
 
+	history := p.globalStore.GetProfileInfo(profileName).History
+
 	// Remove profile from global configuration
 	if err := p.globalStore.RemoveProfile(profileName); err != nil {
 		if errors.Is(err, global.ErrDeletingDefaultProfile) {
-			return ErrCannotDeleteDefaultProfile
+			return p.newProfileError(OpDeleteProfile, profileName, ErrCannotDeleteDefaultProfile)
 		}
 
-		return err
+		return p.newProfileError(OpDeleteProfile, profileName, err)
 
 	}
 
-	return profile.Delete()
+	if err := profile.Delete(); err != nil {
+		return p.newProfileError(OpDeleteProfile, profileName, err)
+	}
+	return p.newProfileError(OpDeleteProfile, profileName, p.deleteRevisions(profileName, history))
 }
 
 // Cleanup attempts to delete all profiles and resources from the profiler's underlying store.
-func (p *Profiler) Cleanup(forceDelete bool) error {
-	if err := p.deleteProfiles(); err != nil { //nolint:empty-block // No logging framework setup
+// When configured WithSoftDelete, the profiles are moved to the trash and the global configuration
+// is kept, unless WithHardDelete is passed to also empty the trash and remove everything.
+func (p *Profiler) Cleanup(forceDelete bool, opts ...cleanupVariadicFunc) error {
+	config := cleanupConfig{hardDelete: !p.config.softDelete}
+	for _, opt := range opts {
+		config = opt(config)
+	}
+
+	if err := p.deleteProfiles(config.hardDelete); err != nil { //nolint:empty-block // No logging framework setup
 		if !forceDelete {
 			return err
 		}
 	}
+	p.currentProfileStore = nil
+	if !config.hardDelete {
+		return nil
+	}
 
+	if err := p.purgeTrash(time.Now().UTC()); err != nil {
+		if !forceDelete {
+			return err
+		}
+	}
 	if err := p.globalStore.DeleteStore(); err != nil {
-		return err
+		return p.newProfileError(OpCleanup, "", mapStoreError(err))
 	}
 
 	// Reset in-memory references and reload a fresh, empty global store
-	p.currentProfileStore = nil
 	p.globalStore = nil
 
 	return nil
 }
 
-// Deletes all profiles for a given profiler.
+// Deletes all profiles for a given profiler. When configured WithSoftDelete, they are moved to the trash.
 func (p *Profiler) DeleteAllProfiles() error {
-	return p.deleteProfiles()
+	return p.newProfileError(OpDeleteAllProfiles, "", p.deleteProfiles(!p.config.softDelete))
 }
 
-func (p *Profiler) deleteProfiles() error {
+func (p *Profiler) deleteProfiles(hardDelete bool) error {
 	if p.globalStore == nil {
 		return nil
 	}
 
-	newStore := newStoreFactory(p.config.driver)
+	if !hardDelete {
+		for _, profileName := range append([]string(nil), p.globalStore.ListProfiles()...) {
+			if err := p.trashProfile(profileName); err != nil {
+				return p.newProfileError(OpDeleteProfile, profileName, err)
+			}
+		}
+		return nil
+	}
+
+	newStore := newStoreFactory(p.config)
 	if newStore == nil {
-		return ErrInvalidStoreDriver
+		return p.newProfileError(OpDeleteAllProfiles, "", ErrInvalidStoreDriver)
 	}
 
 	profiles := append([]string(nil), p.globalStore.ListProfiles()...)
 
 	for _, profileName := range profiles {
+		history := p.globalStore.GetProfileInfo(profileName).History
 		if err := p.globalStore.RemoveProfileForce(profileName); err != nil {
-			return err
+			return p.newProfileError(OpDeleteProfile, profileName, err)
 		}
 
 		store, err := newStore(p.config.configName, getStoreKey(profileName))
 		if err != nil {
-			return err
+			return p.newProfileError(OpDeleteProfile, profileName, err)
 		}
 		if store.Exists() {
 			if err := store.Delete(); err != nil {
-				return errors.Join(fmt.Errorf("%w %q", ErrDeletingProfile, profileName), err)
+				return p.newProfileError(OpDeleteProfile, profileName, errors.Join(fmt.Errorf("%w %q", ErrDeletingProfile, profileName), mapStoreError(err)))
 			}
 		}
+		if err := p.deleteRevisions(profileName, history); err != nil {
+			return p.newProfileError(OpDeleteProfile, profileName, err)
+		}
 	}
 
 	return nil
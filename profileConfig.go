@@ -2,9 +2,11 @@ package profiles
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/jrschumacher/go-osprofiles/internal/global"
 	"github.com/jrschumacher/go-osprofiles/pkg/store"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 type ProfileStore struct {
@@ -13,18 +15,44 @@ type ProfileStore struct {
 	// Profile is the struct that holds the profile data and satisfies the NamedProfile interface.
 	// Exported to allow write/read access to the profile data being stored.
 	Profile NamedProfile
+
+	// envOverrides applies environment variable overrides when loading the profile
+	envOverrides bool
+	overrides    []FieldOverride
+
+	// parents are the stores of the profiles this profile inherits from, root first,
+	// and inherited is their merged data as of the last load
+	parents   []store.StoreInterface
+	inherited json.RawMessage
+
+	// readOnly profiles, such as system profiles, cannot be saved or deleted
+	readOnly bool
+
+	// schema, when set, validates the profile on save and load
+	schema *jsonschema.Schema
+}
+
+// profileLoadOptions configure how a Profiler loads a profile store
+type profileLoadOptions struct {
+	envOverrides bool
+	// parents are the names of the profiles inherited from, root first
+	parents  []string
+	readOnly bool
+	schema   *jsonschema.Schema
 }
 
 // NamedProfile is the holder of a profile containing a name and all stored profile data.
 // It is marshaled on Get and unmarshaled on Set, so an interface is used to allow
 // for any struct to be stored. The struct satisfying the interface must have JSON tags
-// for each stored field.
+// for each stored field. Fields tagged `osprofiles:"secret"` are kept out of plaintext
+// storage when the profiler is configured WithKeyringSecrets.
 //
 // Example:
 //
 //	type MyProfile struct {
 //		 Name string `json:"name"`
 //		 Email string `json:"email"`
+//		 Token string `json:"token" osprofiles:"secret"`
 //	}
 //
 //	func (p *MyProfile) GetName() string {
@@ -54,6 +82,10 @@ func NewProfileStore(serviceNamespace string, newStore store.NewStoreInterface,
 }
 
 func LoadProfileStore[T NamedProfile](serviceNamespace string, newStore store.NewStoreInterface, profileName string) (*ProfileStore, error) {
+	return loadProfileStore[T](serviceNamespace, newStore, profileName, profileLoadOptions{})
+}
+
+func loadProfileStore[T NamedProfile](serviceNamespace string, newStore store.NewStoreInterface, profileName string, opts profileLoadOptions) (*ProfileStore, error) {
 	if err := validateProfileName(profileName); err != nil {
 		return nil, err
 	}
@@ -64,7 +96,17 @@ func LoadProfileStore[T NamedProfile](serviceNamespace string, newStore store.Ne
 	}
 
 	p := &ProfileStore{
-		store: store,
+		store:        store,
+		envOverrides: opts.envOverrides,
+		readOnly:     opts.readOnly,
+		schema:       opts.schema,
+	}
+	for _, parent := range opts.parents {
+		parentStore, err := newStore(serviceNamespace, getStoreKey(parent))
+		if err != nil {
+			return nil, err
+		}
+		p.parents = append(p.parents, parentStore)
 	}
 	_, err = GetStoredProfile[T](p)
 	if err != nil {
@@ -78,21 +120,69 @@ func GetStoredProfile[T NamedProfile](store *ProfileStore) (T, error) {
 	var profile T
 	data, err := store.store.Get()
 	if err != nil {
+		return profile, mapStoreError(err)
+	}
+	if len(store.parents) > 0 {
+		if data, err = store.mergeInherited(data); err != nil {
+			return profile, err
+		}
+	}
+	if err := validateSchema(store.schema, data); err != nil {
 		return profile, err
 	}
-	err = json.Unmarshal(data, &profile)
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+	}
+	store.overrides = nil
+	if store.envOverrides {
+		if store.overrides, err = applyEnvOverrides(&profile, data); err != nil {
+			return profile, err
+		}
+	}
+	afterLoad(&profile)
 	store.Profile = profile
-	return profile, err
+	return profile, nil
 }
 
-// Save the current profile data to the store
+// Save the current profile data to the store. Fields overridden by environment variables
+// keep their stored value unless they were changed after loading, and a profile with a
+// parent only stores the fields that differ from the values it inherits.
 func (p *ProfileStore) Save() error {
-	return p.store.Set(p.Profile)
+	if p.readOnly {
+		return ErrProfileReadOnly
+	}
+	if err := prepareSave(p.Profile); err != nil {
+		return err
+	}
+	profile, err := p.storedProfile()
+	if err != nil {
+		return err
+	}
+	if p.schema != nil {
+		data, err := json.Marshal(profile)
+		if err != nil {
+			return err
+		}
+		if err := validateSchema(p.schema, data); err != nil {
+			return err
+		}
+	}
+	if p.inherited != nil {
+		partial, err := inheritedDiff(p.inherited, profile)
+		if err != nil {
+			return err
+		}
+		return mapStoreError(p.store.Set(partial))
+	}
+	return mapStoreError(p.store.Set(profile))
 }
 
 // Delete the current profile from the store
 func (p *ProfileStore) Delete() error {
-	return p.store.Delete()
+	if p.readOnly {
+		return ErrProfileReadOnly
+	}
+	return mapStoreError(p.store.Delete())
 }
 
 // Profile Name
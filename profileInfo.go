@@ -0,0 +1,68 @@
+package profiles
+
+import (
+	"slices"
+	"time"
+
+	"github.com/jrschumacher/go-osprofiles/internal/global"
+)
+
+// ProfileInfo is library-managed metadata about a profile: when it was created, last updated
+// and last used, plus a free-form description and tags. It is stored in the global configuration.
+type ProfileInfo = global.ProfileInfo
+
+// GetProfileInfo returns the metadata of the specified profile
+func GetProfileInfo(p *Profiler, profileName string) (ProfileInfo, error) {
+	if !p.globalStore.ProfileExists(profileName) {
+		return ProfileInfo{}, p.newProfileError(OpGetProfile, profileName, ErrMissingProfileName)
+	}
+	return p.globalStore.GetProfileInfo(profileName), nil
+}
+
+// SetProfileDescription sets the description of the specified profile
+func SetProfileDescription(p *Profiler, profileName, description string) error {
+	return p.updateProfileInfo(profileName, func(info *ProfileInfo) {
+		info.Description = description
+	})
+}
+
+// SetProfileTags replaces the tags of the specified profile
+func SetProfileTags(p *Profiler, profileName string, tags ...string) error {
+	return p.updateProfileInfo(profileName, func(info *ProfileInfo) {
+		info.Tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	})
+}
+
+// ListProfilesFunc returns the names of the profiles whose metadata satisfies keep
+//
+// Example:
+//
+//	staging := ListProfilesFunc(profiler, func(info ProfileInfo) bool { return info.HasTag("staging") })
+func ListProfilesFunc(p *Profiler, keep func(ProfileInfo) bool) []string {
+	var names []string
+	for _, name := range p.globalStore.ListProfiles() {
+		if keep(p.globalStore.GetProfileInfo(name)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (p *Profiler) updateProfileInfo(profileName string, update func(*ProfileInfo)) error {
+	if !p.globalStore.ProfileExists(profileName) {
+		return p.newProfileError(OpUpdateProfile, profileName, ErrMissingProfileName)
+	}
+	return p.newProfileError(OpUpdateProfile, profileName, p.globalStore.UpdateProfileInfo(profileName, update))
+}
+
+// touchProfileInfo records the time of an update or use of a profile
+func (p *Profiler) touchProfileInfo(profileName string, updated bool) error {
+	now := time.Now().UTC()
+	return p.globalStore.UpdateProfileInfo(profileName, func(info *ProfileInfo) {
+		if updated {
+			info.UpdatedAt = now
+		} else {
+			info.LastUsedAt = now
+		}
+	})
+}
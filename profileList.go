@@ -0,0 +1,67 @@
+package profiles
+
+import (
+	"iter"
+	"sync"
+)
+
+// defaultLoadParallelism bounds concurrent loads in LoadAll when no limit is given
+const defaultLoadParallelism = 4
+
+// ProfileResult is the outcome of loading a single profile with LoadAll.
+type ProfileResult[T NamedProfile] struct {
+	Name    string
+	Profile T
+	Err     error
+}
+
+// AllProfiles iterates over all stored profiles as name and profile pairs, in the order of ListProfiles.
+// Profiles that fail to load are skipped; use LoadAll to inspect per-profile errors.
+//
+// Example:
+//
+//	for name, profile := range AllProfiles[*MyProfile](profiler) {
+//		fmt.Println(name, profile.Endpoint)
+//	}
+func AllProfiles[T NamedProfile](p *Profiler) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		for _, name := range ListProfiles(p) {
+			profile, err := typedProfile[T](GetProfile[T](p, name))
+			if err != nil {
+				continue
+			}
+			if !yield(name, profile) {
+				return
+			}
+		}
+	}
+}
+
+// LoadAll loads all stored profiles concurrently, with at most maxParallel loads in flight
+// (a default is used when maxParallel < 1). Results are returned in the order of ListProfiles,
+// and a profile that fails to load reports its error without aborting the others.
+func LoadAll[T NamedProfile](p *Profiler, maxParallel int) []ProfileResult[T] {
+	if maxParallel < 1 {
+		maxParallel = defaultLoadParallelism
+	}
+
+	names := append([]string(nil), ListProfiles(p)...)
+	results := make([]ProfileResult[T], len(names))
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			profile, err := typedProfile[T](GetProfile[T](p, name))
+			results[i] = ProfileResult[T]{Name: name, Profile: profile, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
@@ -1,11 +1,18 @@
 package profiles
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jrschumacher/go-osprofiles/internal/global"
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/zalando/go-keyring"
 )
@@ -302,9 +309,1297 @@ func (s *ProfilesSuite) TestLifecycleProfile_Keyring() {
 	s.assertKeyringProfiles(true, global.STORE_KEY_GLOBAL, profile.Name, profile2.Name)
 }
 
+func (s *ProfilesSuite) TestLifecycleProfile_PlainFileStore() {
+	dir := s.T().TempDir()
+	profiler, err := New(testConsumerServiceProfiler, WithPlainFileStore(dir))
+	s.Require().NoError(err)
+
+	profile := &mockProfile{
+		Name:      "test-profile-plain",
+		TestValue: "test-value-plain",
+	}
+	s.Require().NoError(profiler.AddProfile(profile, true))
+	s.assertDirFileCount(dir, 2)
+
+	p, err := GetProfile[*mockProfile](profiler, profile.Name)
+	s.Require().NoError(err)
+	s.Require().Equal(profile.TestValue, p.Profile.(*mockProfile).TestValue)
+
+	// stored values are human readable
+	data, err := os.ReadFile(filepath.Join(dir, "urn.goosprofiles."+testConsumerServiceProfiler+".profile.v1."+getStoreKey(profile.Name)+".json"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), profile.TestValue)
+
+	s.Require().NoError(profiler.Cleanup(true))
+	s.assertDirFileCount(dir, 0)
+}
+
+type mockSecretProfile struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Token    string `json:"token" osprofiles:"secret"`
+}
+
+func (p *mockSecretProfile) GetName() string {
+	return p.Name
+}
+
+func (s *ProfilesSuite) TestLifecycleProfile_KeyringSecrets() {
+	dir := s.T().TempDir()
+	profiler, err := New(testConsumerServiceProfiler, WithPlainFileStore(dir), WithKeyringSecrets())
+	s.Require().NoError(err)
+
+	profile := &mockSecretProfile{
+		Name:     "test-profile-split",
+		Endpoint: "https://example.com",
+		Token:    "super-secret-token",
+	}
+	s.Require().NoError(profiler.AddProfile(profile, true))
+
+	// only non-secret fields are written to disk
+	data, err := os.ReadFile(filepath.Join(dir, "urn.goosprofiles."+testConsumerServiceProfiler+".profile.v1."+getStoreKey(profile.Name)+".json"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), profile.Endpoint)
+	s.Require().NotContains(string(data), profile.Token)
+	s.assertKeyringProfiles(false, profile.Name)
+
+	p, err := GetProfile[*mockSecretProfile](profiler, profile.Name)
+	s.Require().NoError(err)
+	s.Require().Equal(profile, p.Profile.(*mockSecretProfile))
+
+	s.Require().NoError(profiler.Cleanup(true))
+	s.assertDirFileCount(dir, 0)
+	s.assertKeyringProfiles(true, profile.Name)
+}
+
 func TestAttributesSuite(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping profiles test suite")
 	}
 	suite.Run(t, new(ProfilesSuite))
 }
+
+func TestWithFallbackStores(t *testing.T) {
+	unavailable := func(string, string, ...store.DriverOpt) (store.StoreInterface, error) {
+		return nil, errors.New("no secret service")
+	}
+
+	profiler, err := New("test-fallback-stores", WithFallbackStores(WithCustomStore(unavailable), WithInMemoryStore()))
+	require.NoError(t, err)
+
+	selection := GetStoreSelection(profiler)
+	require.Equal(t, string(global.PROFILE_DRIVER_IN_MEMORY), selection.Driver)
+	require.True(t, selection.Fallback)
+	require.ErrorContains(t, selection.Err, "no secret service")
+
+	// profiles round-trip through the fallback store
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "fallback"}, true))
+	fallback, err := GetProfile[*mockProfile](profiler, "fallback")
+	require.NoError(t, err)
+	require.Equal(t, "fallback", fallback.GetProfileName())
+
+	_, err = New("test-fallback-stores", WithFallbackStores(WithCustomStore(unavailable)))
+	require.ErrorIs(t, err, ErrNoAvailableStore)
+
+	// profilers of the same configuration share the in-memory profiles
+	hasGlobalStore, err := HasGlobalStore("test-fallback-stores", WithInMemoryStore())
+	require.NoError(t, err)
+	require.True(t, hasGlobalStore)
+	profiler, err = New("test-fallback-stores", WithInMemoryStore())
+	require.NoError(t, err)
+	require.Equal(t, StoreSelection{Driver: string(global.PROFILE_DRIVER_IN_MEMORY)}, GetStoreSelection(profiler))
+	require.Equal(t, []string{"fallback"}, ListProfiles(profiler))
+	require.NoError(t, profiler.Cleanup(true))
+
+	// a driver whose secret store is unavailable is skipped
+	withUnavailableSecrets := func(c profileConfig) profileConfig {
+		c = WithPlainFileStore(t.TempDir())(c)
+		c.secretStore = unavailable
+		return c
+	}
+	profiler, err = New("test-fallback-stores", WithFallbackStores(withUnavailableSecrets, WithInMemoryStore()))
+	require.NoError(t, err)
+	selection = GetStoreSelection(profiler)
+	require.Equal(t, string(global.PROFILE_DRIVER_IN_MEMORY), selection.Driver)
+	require.ErrorContains(t, selection.Err, "no secret service")
+
+	// the probe leaves no encryption key behind in the keyring
+	_, err = New("test-fallback-stores", WithFallbackStores(WithFileStore(t.TempDir())))
+	require.NoError(t, err)
+	_, err = keyring.Get(store.BuildNamespaceURN("test-fallback-stores", "v1"), global.STORE_KEY_PROBE)
+	require.ErrorIs(t, err, keyring.ErrNotFound)
+}
+
+func TestLoadProfileStore_ErrorMapping(t *testing.T) {
+	_, err := LoadProfileStore[*mockProfile]("test-error-mapping", store.NewMemoryStore, "missing")
+	require.ErrorIs(t, err, ErrMissingProfileName)
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	corrupt := func(ns, key string, opts ...store.DriverOpt) (store.StoreInterface, error) {
+		s, err := store.NewMemoryStore(ns, key, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return s, s.Set("not a profile")
+	}
+	_, err = LoadProfileStore[*mockProfile]("test-error-mapping", corrupt, "corrupt")
+	require.ErrorIs(t, err, ErrProfileCorrupt)
+}
+
+func TestProfileError(t *testing.T) {
+	profiler, err := New("test-profile-error", WithInMemoryStore())
+	require.NoError(t, err)
+
+	profile := &mockProfile{Name: "test-profile-error"}
+	require.NoError(t, profiler.AddProfile(profile, true))
+
+	err = profiler.AddProfile(profile, false)
+	require.ErrorIs(t, err, ErrProfileNameConflict)
+
+	var profileErr *ProfileError
+	require.ErrorAs(t, err, &profileErr)
+	require.Equal(t, OpAddProfile, profileErr.Op)
+	require.Equal(t, profile.Name, profileErr.Profile)
+	require.Equal(t, "test-profile-error", profileErr.Namespace)
+	require.Equal(t, string(global.PROFILE_DRIVER_IN_MEMORY), profileErr.Driver)
+	require.Contains(t, err.Error(), `add profile "test-profile-error"`)
+
+	err = DeleteProfile[*mockProfile](profiler, "missing")
+	require.ErrorIs(t, err, ErrMissingProfileName)
+	require.ErrorAs(t, err, &profileErr)
+	require.Equal(t, OpDeleteProfile, profileErr.Op)
+	require.Equal(t, "missing", profileErr.Profile)
+
+	_, err = UseProfile[*mockProfile](profiler, "missing")
+	require.ErrorAs(t, err, &profileErr)
+	require.Equal(t, OpUseProfile, profileErr.Op)
+	require.Equal(t, "missing", profileErr.Profile)
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestTypedProfiler(t *testing.T) {
+	profiler, err := New("test-typed-profiler", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	profiles := NewTypedProfiler[*mockProfile](profiler)
+	require.Same(t, profiler, profiles.Profiler())
+
+	first := &mockProfile{Name: "first", TestValue: "one"}
+	second := &mockProfile{Name: "second", TestValue: "two"}
+	require.NoError(t, profiles.Add(first, true))
+	require.NoError(t, profiles.Add(second, false))
+
+	got, err := profiles.Get(second.Name)
+	require.NoError(t, err)
+	require.Equal(t, second, got)
+
+	current, err := profiles.UseDefault()
+	require.NoError(t, err)
+	require.Equal(t, first, current)
+
+	current.TestValue = "updated"
+	require.NoError(t, profiles.Update(current))
+	current, err = profiles.Current()
+	require.NoError(t, err)
+	require.Equal(t, "updated", current.TestValue)
+
+	list, err := profiles.List()
+	require.NoError(t, err)
+	require.Equal(t, []*mockProfile{current, second}, list)
+
+	require.NoError(t, profiles.SetDefault(second.Name))
+	require.NoError(t, profiles.Delete(first.Name))
+	list, err = profiles.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	// a profile stored as another type is reported rather than panicking
+	_, err = NewTypedProfiler[*mockSecretProfile](profiler).Current()
+	require.ErrorIs(t, err, ErrProfileTypeMismatch)
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestAllProfiles(t *testing.T) {
+	dir := t.TempDir()
+	profiler, err := New("test-all-profiles", WithPlainFileStore(dir))
+	require.NoError(t, err)
+
+	names := []string{"alpha", "bravo", "charlie", "delta", "echo"}
+	for _, name := range names {
+		require.NoError(t, profiler.AddProfile(&mockProfile{Name: name, TestValue: name + "-value"}, false))
+	}
+
+	var iterated []string
+	for name, profile := range AllProfiles[*mockProfile](profiler) {
+		require.Equal(t, name+"-value", profile.TestValue)
+		iterated = append(iterated, name)
+	}
+	require.Equal(t, names, iterated)
+
+	// stops when the loop breaks
+	count := 0
+	for range NewTypedProfiler[*mockProfile](profiler).All() {
+		count++
+		break
+	}
+	require.Equal(t, 1, count)
+
+	// break one profile on disk and load the rest concurrently
+	broken := filepath.Join(dir, store.BuildNamespaceURN("test-all-profiles", "v1")+"."+getStoreKey("charlie")+".json")
+	require.NoError(t, os.WriteFile(broken, []byte("{not json"), 0o600))
+
+	results := LoadAll[*mockProfile](profiler, 2)
+	require.Len(t, results, len(names))
+	for i, result := range results {
+		require.Equal(t, names[i], result.Name)
+		if result.Name == "charlie" {
+			require.ErrorIs(t, result.Err, ErrProfileCorrupt)
+			continue
+		}
+		require.NoError(t, result.Err)
+		require.Equal(t, result.Name+"-value", result.Profile.TestValue)
+	}
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestLoadAll_FileStore(t *testing.T) {
+	profiler, err := New("test-load-all-file", WithFileStore(t.TempDir()), WithFileStorePassphrase("correct horse battery staple"))
+	require.NoError(t, err)
+
+	names := []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot"}
+	for _, name := range names {
+		require.NoError(t, profiler.AddProfile(&mockProfile{Name: name, TestValue: name + "-value"}, false))
+	}
+
+	results := LoadAll[*mockProfile](profiler, len(names))
+	require.Len(t, results, len(names))
+	for i, result := range results {
+		require.Equal(t, names[i], result.Name)
+		require.NoError(t, result.Err)
+		require.Equal(t, result.Name+"-value", result.Profile.TestValue)
+	}
+}
+
+func TestProfileInfo(t *testing.T) {
+	dir := t.TempDir()
+	profiler, err := New("test-profile-info", WithPlainFileStore(dir))
+	require.NoError(t, err)
+
+	profile := &mockProfile{Name: "info"}
+	require.NoError(t, profiler.AddProfile(profile, true))
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "other"}, false))
+
+	info, err := GetProfileInfo(profiler, profile.Name)
+	require.NoError(t, err)
+	require.Equal(t, profile.Name, info.Name)
+	require.False(t, info.CreatedAt.IsZero())
+	require.Equal(t, info.CreatedAt, info.UpdatedAt)
+	require.True(t, info.LastUsedAt.IsZero())
+
+	_, err = UseProfile[*mockProfile](profiler, profile.Name)
+	require.NoError(t, err)
+	profile.TestValue = "updated"
+	require.NoError(t, UpdateCurrentProfile(profiler, profile))
+	require.NoError(t, SetProfileDescription(profiler, profile.Name, "primary account"))
+	require.NoError(t, SetProfileTags(profiler, profile.Name, "staging", "eu", "staging"))
+
+	// metadata is persisted in the global configuration
+	reloaded, err := New("test-profile-info", WithPlainFileStore(dir))
+	require.NoError(t, err)
+
+	info, err = GetProfileInfo(reloaded, profile.Name)
+	require.NoError(t, err)
+	require.False(t, info.LastUsedAt.IsZero())
+	require.False(t, info.UpdatedAt.Before(info.CreatedAt))
+	require.Equal(t, "primary account", info.Description)
+	require.Equal(t, []string{"eu", "staging"}, info.Tags)
+
+	require.Equal(t, []string{profile.Name}, ListProfilesFunc(profiler, func(info ProfileInfo) bool { return info.HasTag("staging") }))
+
+	// using the current profile again records the use
+	_, err = UseProfile[*mockProfile](profiler, profile.Name)
+	require.NoError(t, err)
+	info, err = GetProfileInfo(profiler, profile.Name)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = UseProfile[*mockProfile](profiler, profile.Name)
+	require.NoError(t, err)
+	used, err := GetProfileInfo(profiler, profile.Name)
+	require.NoError(t, err)
+	require.True(t, used.LastUsedAt.After(info.LastUsedAt))
+
+	_, err = GetProfileInfo(profiler, "missing")
+	require.ErrorIs(t, err, ErrMissingProfileName)
+	require.ErrorIs(t, SetProfileTags(profiler, "missing", "x"), ErrMissingProfileName)
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestResolveActiveProfile(t *testing.T) {
+	dir := t.TempDir()
+	const envVar = "TEST_OSPROFILES_PROFILE"
+
+	profiler, err := New("test-active-profile", WithPlainFileStore(dir), WithProfileEnvVar(envVar))
+	require.NoError(t, err)
+
+	_, err = ResolveActiveProfile(profiler)
+	require.ErrorIs(t, err, ErrMissingDefaultProfile)
+
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "default"}, true))
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "from-env"}, false))
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "from-flag"}, false))
+
+	active, err := ResolveActiveProfile(profiler)
+	require.NoError(t, err)
+	require.Equal(t, ActiveProfile{Name: "default", Source: ProfileSourceDefault}, active)
+
+	t.Setenv(envVar, "from-env")
+	active, err = ResolveActiveProfile(profiler)
+	require.NoError(t, err)
+	require.Equal(t, ActiveProfile{Name: "from-env", Source: ProfileSourceEnv, Origin: envVar}, active)
+
+	current, err := UseDefaultProfile[*mockProfile](profiler)
+	require.NoError(t, err)
+	require.Equal(t, "from-env", current.GetProfileName())
+
+	flagged, err := New("test-active-profile", WithPlainFileStore(dir), WithProfileEnvVar(envVar), WithProfileFlag("from-flag"))
+	require.NoError(t, err)
+	active, err = ResolveActiveProfile(flagged)
+	require.NoError(t, err)
+	require.Equal(t, ActiveProfile{Name: "from-flag", Source: ProfileSourceFlag}, active)
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestPinProfile(t *testing.T) {
+	const pinFile = ".test-osprofiles-profile"
+	project := t.TempDir()
+	nested := filepath.Join(project, "src", "pkg")
+	require.NoError(t, os.MkdirAll(nested, 0o700))
+
+	profiler, err := New("test-pin-profile", WithPlainFileStore(t.TempDir()), WithProfilePinFile(pinFile))
+	require.NoError(t, err)
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "default"}, true))
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "project"}, false))
+
+	require.ErrorIs(t, PinProfile(profiler, project, "missing"), ErrMissingProfileName)
+	require.NoError(t, PinProfile(profiler, project, "project"))
+
+	active, err := findPinnedProfile(nested, pinFile)
+	require.NoError(t, err)
+	require.Equal(t, ActiveProfile{Name: "project", Source: ProfileSourceDirectory, Origin: filepath.Join(project, pinFile)}, active)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(nested))
+	t.Cleanup(func() { require.NoError(t, os.Chdir(wd)) })
+
+	current, err := UseDefaultProfile[*mockProfile](profiler)
+	require.NoError(t, err)
+	require.Equal(t, "project", current.GetProfileName())
+
+	require.NoError(t, UnpinProfile(profiler, project))
+	require.NoError(t, UnpinProfile(profiler, project))
+	active, err = ResolveActiveProfile(profiler)
+	require.NoError(t, err)
+	require.Equal(t, ActiveProfile{Name: "default", Source: ProfileSourceDefault}, active)
+
+	// invalid pinned names are reported with the pin file
+	require.NoError(t, os.WriteFile(filepath.Join(project, pinFile), []byte("Not Valid"), 0o600))
+	_, err = ResolveActiveProfile(profiler)
+	require.ErrorContains(t, err, filepath.Join(project, pinFile))
+	require.NoError(t, profiler.Cleanup(true))
+
+	unconfigured, err := New("test-pin-profile", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	require.ErrorIs(t, PinProfile(unconfigured, project, "project"), ErrProfilePinFileNotSet)
+}
+
+type mockEnvProfile struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint" env:"TEST_OSPROFILES_ENDPOINT"`
+	Retries  int    `json:"retries" env:"TEST_OSPROFILES_RETRIES"`
+	Token    string `json:"token" env:"TEST_OSPROFILES_TOKEN"`
+}
+
+func (p *mockEnvProfile) GetName() string {
+	return p.Name
+}
+
+func TestEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	profiler, err := New("test-env-overrides", WithPlainFileStore(dir), WithEnvOverrides())
+	require.NoError(t, err)
+
+	stored := &mockEnvProfile{Name: "ci", Endpoint: "https://stored.example.com", Retries: 1, Token: "stored"}
+	require.NoError(t, profiler.AddProfile(stored, true))
+
+	t.Setenv("TEST_OSPROFILES_ENDPOINT", "https://override.example.com")
+	t.Setenv("TEST_OSPROFILES_RETRIES", "5")
+	t.Setenv("TEST_OSPROFILES_TOKEN", "override")
+
+	// reload so the profile is read back from storage
+	profiler, err = New("test-env-overrides", WithPlainFileStore(dir), WithEnvOverrides())
+	require.NoError(t, err)
+	current, err := UseProfile[*mockEnvProfile](profiler, stored.Name)
+	require.NoError(t, err)
+	effective := current.Profile.(*mockEnvProfile)
+	require.Equal(t, "https://override.example.com", effective.Endpoint)
+	require.Equal(t, 5, effective.Retries)
+
+	overrides := current.EnvOverrides()
+	require.Len(t, overrides, 3)
+	require.Equal(t, "endpoint", overrides[0].Field)
+	require.Equal(t, "TEST_OSPROFILES_ENDPOINT", overrides[0].EnvVar)
+
+	// saving keeps stored values for overridden fields that were not changed
+	effective.Token = "changed"
+	require.NoError(t, UpdateCurrentProfile(profiler, effective))
+
+	plain, err := New("test-env-overrides", WithPlainFileStore(dir))
+	require.NoError(t, err)
+	p, err := GetProfile[*mockEnvProfile](plain, stored.Name)
+	require.NoError(t, err)
+	require.Empty(t, p.EnvOverrides())
+	require.Equal(t, &mockEnvProfile{Name: "ci", Endpoint: "https://stored.example.com", Retries: 1, Token: "changed"}, p.Profile)
+
+	t.Setenv("TEST_OSPROFILES_RETRIES", "many")
+	_, err = GetProfile[*mockEnvProfile](profiler, stored.Name)
+	require.ErrorIs(t, err, ErrInvalidEnvOverride)
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+type mockLayeredProfile struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Options  struct {
+		A string `json:"a,omitempty"`
+		B string `json:"b,omitempty"`
+	} `json:"options"`
+}
+
+func (p *mockLayeredProfile) GetName() string {
+	return p.Name
+}
+
+func TestProfileInheritance(t *testing.T) {
+	dir := t.TempDir()
+	profiler, err := New("test-inheritance", WithPlainFileStore(dir))
+	require.NoError(t, err)
+	require.NoError(t, profiler.AddProfile(&mockLayeredProfile{Name: "default"}, true))
+
+	base := &mockLayeredProfile{Name: "base", Endpoint: "https://base.example.com", Region: "us"}
+	base.Options.A = "base-a"
+	base.Options.B = "base-b"
+	require.NoError(t, profiler.AddProfile(base, false))
+
+	child := &mockLayeredProfile{Name: "child", Region: "eu"}
+	child.Options.B = "child-b"
+	require.NoError(t, profiler.AddProfile(child, false))
+	require.NoError(t, SetProfileParent(profiler, child.Name, base.Name))
+	require.Equal(t, []string{child.Name}, ListProfileChildren(profiler, base.Name))
+
+	effective, err := NewTypedProfiler[*mockLayeredProfile](profiler).Use(child.Name)
+	require.NoError(t, err)
+	require.Equal(t, "https://base.example.com", effective.Endpoint)
+	require.Equal(t, "eu", effective.Region)
+	require.Equal(t, "base-a", effective.Options.A)
+	require.Equal(t, "child-b", effective.Options.B)
+
+	// only fields that differ from the parent are stored for the child
+	effective.Endpoint = "https://child.example.com"
+	require.NoError(t, UpdateCurrentProfile(profiler, effective))
+	data, err := os.ReadFile(filepath.Join(dir, store.BuildNamespaceURN("test-inheritance", "v1")+"."+getStoreKey(child.Name)+".json"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"child","endpoint":"https://child.example.com","region":"eu","options":{"b":"child-b"}}`, string(data))
+
+	// later parent changes are inherited
+	grandparent := &mockLayeredProfile{Name: "grandparent"}
+	grandparent.Options.A = "grandparent-a"
+	require.NoError(t, profiler.AddProfile(grandparent, false))
+	require.NoError(t, SetProfileParent(profiler, base.Name, grandparent.Name))
+	base.Options.A = ""
+	_, err = UseProfile[*mockLayeredProfile](profiler, base.Name)
+	require.NoError(t, err)
+	require.NoError(t, UpdateCurrentProfile(profiler, base))
+
+	p, err := GetProfile[*mockLayeredProfile](profiler, child.Name)
+	require.NoError(t, err)
+	require.Equal(t, "grandparent-a", p.Profile.(*mockLayeredProfile).Options.A)
+	require.Equal(t, "https://child.example.com", p.Profile.(*mockLayeredProfile).Endpoint)
+
+	require.ErrorIs(t, SetProfileParent(profiler, grandparent.Name, child.Name), ErrProfileInheritanceCycle)
+	require.ErrorIs(t, SetProfileParent(profiler, child.Name, child.Name), ErrProfileInheritanceCycle)
+	require.ErrorIs(t, SetProfileParent(profiler, child.Name, "missing"), ErrMissingProfileName)
+
+	require.ErrorIs(t, DeleteProfile[*mockLayeredProfile](profiler, base.Name), ErrProfileHasChildren)
+	require.NoError(t, SetProfileParent(profiler, child.Name, ""))
+	require.NoError(t, DeleteProfile[*mockLayeredProfile](profiler, base.Name))
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestSystemProfiles(t *testing.T) {
+	const configName = "test-system-profiles"
+	systemDir := t.TempDir()
+	userDir := t.TempDir()
+
+	// an administrator prepares the system profiles
+	admin, err := New(configName, WithPlainFileStore(systemDir))
+	require.NoError(t, err)
+	require.NoError(t, admin.AddProfile(&mockProfile{Name: "managed", TestValue: "system"}, true))
+	require.NoError(t, admin.AddProfile(&mockProfile{Name: "shadowed", TestValue: "system"}, false))
+
+	profiler, err := New(configName, WithPlainFileStore(userDir), WithSystemProfiles(systemDir))
+	require.NoError(t, err)
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "mine", TestValue: "user"}, false))
+	require.NoError(t, profiler.AddProfile(&mockProfile{Name: "shadowed", TestValue: "user"}, false))
+
+	require.Equal(t, []string{"mine", "shadowed", "managed"}, ListProfiles(profiler))
+	require.True(t, IsSystemProfile(profiler, "managed"))
+	require.False(t, IsSystemProfile(profiler, "shadowed"))
+
+	profiles := NewTypedProfiler[*mockProfile](profiler)
+	managed, err := profiles.Use("managed")
+	require.NoError(t, err)
+	require.Equal(t, "system", managed.TestValue)
+	userGlobalFile := filepath.Join(userDir, store.BuildNamespaceURN(configName, "v1")+"."+global.STORE_KEY_GLOBAL+".json")
+	data, err := os.ReadFile(userGlobalFile)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), `"managed"`)
+	shadowed, err := profiles.Get("shadowed")
+	require.NoError(t, err)
+	require.Equal(t, "user", shadowed.TestValue)
+
+	// system profiles are read-only
+	managed.TestValue = "changed"
+	require.ErrorIs(t, profiles.Update(managed), ErrProfileReadOnly)
+	require.ErrorIs(t, profiles.Delete("managed"), ErrProfileReadOnly)
+
+	// system profiles can be pinned
+	pinning, err := New(configName, WithPlainFileStore(userDir), WithSystemProfiles(systemDir), WithProfilePinFile(".test-profile"))
+	require.NoError(t, err)
+	require.NoError(t, PinProfile(pinning, t.TempDir(), "managed"))
+
+	require.NoError(t, SetDefaultProfile(profiler, "managed"))
+	require.NoError(t, SetDefaultProfile(profiler, "mine"))
+
+	// lock the default profile in the system configuration
+	globalFile := filepath.Join(systemDir, store.BuildNamespaceURN(configName, "v1")+"."+global.STORE_KEY_GLOBAL+".json")
+	data, err = os.ReadFile(globalFile)
+	require.NoError(t, err)
+	var systemConfig map[string]any
+	require.NoError(t, json.Unmarshal(data, &systemConfig))
+	systemConfig["lockDefaultProfile"] = true
+	data, err = json.Marshal(systemConfig)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(globalFile, data, 0o600))
+
+	profiler, err = New(configName, WithPlainFileStore(userDir), WithSystemProfiles(systemDir))
+	require.NoError(t, err)
+	active, err := ResolveActiveProfile(profiler)
+	require.NoError(t, err)
+	require.Equal(t, "managed", active.Name)
+	require.ErrorIs(t, SetDefaultProfile(profiler, "mine"), ErrDefaultProfileLocked)
+	err = profiler.AddProfile(&mockProfile{Name: "new", TestValue: "user"}, true)
+	require.ErrorIs(t, err, ErrProfileReadOnly)
+	require.ErrorIs(t, err, ErrDefaultProfileLocked)
+	require.NotContains(t, ListProfiles(profiler), "new")
+
+	// the lock also overrides the profile flag, environment variable and pin file
+	profiler, err = New(configName, WithPlainFileStore(userDir), WithSystemProfiles(systemDir), WithProfileFlag("mine"))
+	require.NoError(t, err)
+	active, err = ResolveActiveProfile(profiler)
+	require.NoError(t, err)
+	require.Equal(t, ActiveProfile{Name: "managed", Source: ProfileSourceDefault, Origin: systemDir}, active)
+
+	// a missing system directory is an empty layer
+	profiler, err = New(configName, WithPlainFileStore(userDir), WithSystemProfiles(filepath.Join(systemDir, "missing")))
+	require.NoError(t, err)
+	require.Equal(t, []string{"mine", "shadowed"}, ListProfiles(profiler))
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestExportImport(t *testing.T) {
+	source, err := New("test-bundle-source", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	require.NoError(t, source.AddProfile(&mockProfile{Name: "dev", TestValue: "dev-value"}, false))
+	require.NoError(t, source.AddProfile(&mockProfile{Name: "prod", TestValue: "prod-value"}, true))
+	require.NoError(t, SetProfileDescription(source, "prod", "production"))
+
+	var plain, sealed bytes.Buffer
+	require.NoError(t, Export(source, &plain, ExportOptions{}))
+	require.Contains(t, plain.String(), "prod-value")
+	require.NoError(t, Export(source, &sealed, ExportOptions{Profiles: []string{"prod"}, Passphrase: "correct horse"}))
+	require.NotContains(t, sealed.String(), "prod-value")
+	require.ErrorIs(t, Export(source, &bytes.Buffer{}, ExportOptions{Profiles: []string{"missing"}}), ErrMissingProfileName)
+
+	target, err := New("test-bundle-target", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	require.NoError(t, target.AddProfile(&mockProfile{Name: "dev", TestValue: "existing"}, true))
+
+	// a dry run reports the planned actions without importing
+	results, err := Import[*mockProfile](target, bytes.NewReader(plain.Bytes()), ImportOptions{OnConflict: ConflictRename, DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, []ImportResult{
+		{Name: "dev", ImportedAs: "dev-imported", Action: ImportRenamed},
+		{Name: "prod", ImportedAs: "prod", Action: ImportAdded},
+	}, results)
+	require.Equal(t, []string{"dev"}, ListProfiles(target))
+
+	results, err = Import[*mockProfile](target, bytes.NewReader(plain.Bytes()), ImportOptions{OnConflict: ConflictRename, SetDefault: true})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	profiles := NewTypedProfiler[*mockProfile](target)
+	renamed, err := profiles.Get("dev-imported")
+	require.NoError(t, err)
+	require.Equal(t, "dev-value", renamed.TestValue)
+	info, err := GetProfileInfo(target, "prod")
+	require.NoError(t, err)
+	require.Equal(t, "production", info.Description)
+	require.Equal(t, "prod", target.globalStore.GetDefaultProfile())
+
+	// skip keeps the existing profile, overwrite replaces it
+	results, err = Import[*mockProfile](target, bytes.NewReader(plain.Bytes()), ImportOptions{})
+	require.NoError(t, err)
+	require.Equal(t, ImportSkipped, results[0].Action)
+	_, err = Import[*mockProfile](target, bytes.NewReader(plain.Bytes()), ImportOptions{OnConflict: ConflictOverwrite})
+	require.NoError(t, err)
+	dev, err := profiles.Get("dev")
+	require.NoError(t, err)
+	require.Equal(t, "dev-value", dev.TestValue)
+
+	// sealed bundles require the passphrase
+	_, err = Import[*mockProfile](target, bytes.NewReader(sealed.Bytes()), ImportOptions{})
+	require.ErrorIs(t, err, store.ErrPassphraseRequired)
+	_, err = Import[*mockProfile](target, bytes.NewReader(sealed.Bytes()), ImportOptions{Passphrase: "wrong"})
+	require.ErrorIs(t, err, ErrProfileDecryption)
+	results, err = Import[*mockProfile](target, bytes.NewReader(sealed.Bytes()), ImportOptions{Passphrase: "correct horse", OnConflict: ConflictRename})
+	require.NoError(t, err)
+	require.Equal(t, []ImportResult{{Name: "prod", ImportedAs: "prod-imported", Action: ImportRenamed}}, results)
+
+	_, err = Import[*mockProfile](target, bytes.NewReader([]byte("not a bundle")), ImportOptions{})
+	require.ErrorIs(t, err, ErrInvalidBundle)
+
+	require.NoError(t, source.Cleanup(true))
+	require.NoError(t, target.Cleanup(true))
+}
+
+func TestImportOverwrite(t *testing.T) {
+	source, err := New("test-bundle-overwrite-source", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	require.NoError(t, source.AddProfile(&mockLayeredProfile{Name: "a", Endpoint: "source-a"}, true))
+	require.NoError(t, source.AddProfile(&mockLayeredProfile{Name: "b", Endpoint: "source-b"}, false))
+	require.NoError(t, SetProfileParent(source, "b", "a"))
+	var bundle bytes.Buffer
+	require.NoError(t, Export(source, &bundle, ExportOptions{Profiles: []string{"b"}}))
+
+	target, err := New("test-bundle-overwrite-target", WithPlainFileStore(t.TempDir()), WithProfileHistory(HistoryRetention{MaxRevisions: 5}))
+	require.NoError(t, err)
+	profiles := NewTypedProfiler[*mockLayeredProfile](target)
+	require.NoError(t, profiles.Add(&mockLayeredProfile{Name: "a", Endpoint: "target-a"}, true))
+	require.NoError(t, profiles.Add(&mockLayeredProfile{Name: "b", Endpoint: "target-b"}, false))
+
+	// b inheriting from a, which inherits from b, is a cycle
+	require.NoError(t, SetProfileParent(target, "a", "b"))
+	_, err = Import[*mockLayeredProfile](target, bytes.NewReader(bundle.Bytes()), ImportOptions{OnConflict: ConflictOverwrite})
+	require.ErrorIs(t, err, ErrProfileInheritanceCycle)
+	info, err := GetProfileInfo(target, "b")
+	require.NoError(t, err)
+	require.Empty(t, info.Parent)
+
+	// the overwritten value is kept as a revision
+	require.NoError(t, SetProfileParent(target, "a", ""))
+	_, err = Import[*mockLayeredProfile](target, bytes.NewReader(bundle.Bytes()), ImportOptions{OnConflict: ConflictOverwrite})
+	require.NoError(t, err)
+	imported, err := profiles.Get("b")
+	require.NoError(t, err)
+	require.Equal(t, "source-b", imported.Endpoint)
+	history, err := ProfileHistory(target, "b")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.NoError(t, RestoreProfile[*mockLayeredProfile](target, "b", history[0].Revision))
+	restored, err := profiles.Get("b")
+	require.NoError(t, err)
+	require.Equal(t, "target-b", restored.Endpoint)
+
+	require.NoError(t, source.Cleanup(true))
+	require.NoError(t, target.Cleanup(true))
+}
+
+type mockRedactedProfile struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Token    string `json:"token" osprofiles:"secret"`
+	Auth     struct {
+		User     string `json:"user"`
+		Password string `json:"password,omitempty" osprofiles:"secret"`
+	} `json:"auth"`
+	Port int `json:"port"`
+}
+
+func (p *mockRedactedProfile) GetName() string {
+	return p.Name
+}
+
+func TestExportRedacted(t *testing.T) {
+	profiler, err := New("test-redacted", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	profile := &mockRedactedProfile{Name: "prod", Endpoint: "https://example.com", Token: "s3cr3t", Port: 8443}
+	profile.Auth.User = "admin"
+	profile.Auth.Password = "hunter2"
+	require.NoError(t, profiler.AddProfile(profile, true))
+	require.NoError(t, profiler.AddProfile(&mockRedactedProfile{Name: "dev", Endpoint: "http://localhost"}, false))
+
+	var out bytes.Buffer
+	require.NoError(t, ExportRedactedProfile[*mockRedactedProfile](profiler, &out, "prod", RedactOptions{}))
+	require.JSONEq(t, `{"name":"prod","endpoint":"https://example.com","token":"<redacted>","auth":{"user":"admin","password":"<redacted>"},"port":8443}`, out.String())
+
+	out.Reset()
+	require.NoError(t, ExportRedactedProfile[*mockRedactedProfile](profiler, &out, "prod", RedactOptions{
+		Format:      ExportFormatYAML,
+		Placeholder: "***",
+		Redact:      func(path string) bool { return path == "auth.user" },
+	}))
+	require.Contains(t, out.String(), "user: '***'")
+	require.Contains(t, out.String(), "port: 8443")
+	require.NotContains(t, out.String(), "hunter2")
+
+	out.Reset()
+	require.NoError(t, ExportRedacted[*mockRedactedProfile](profiler, &out, RedactOptions{}))
+	var dump struct {
+		Namespace      string                    `json:"namespace"`
+		DefaultProfile string                    `json:"defaultProfile"`
+		Profiles       map[string]map[string]any `json:"profiles"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &dump))
+	require.Equal(t, "test-redacted", dump.Namespace)
+	require.Equal(t, "prod", dump.DefaultProfile)
+	require.Equal(t, "<redacted>", dump.Profiles["prod"]["token"])
+	require.Equal(t, "<redacted>", dump.Profiles["dev"]["token"])
+	require.NotContains(t, dump.Profiles["dev"]["auth"], "password")
+
+	require.ErrorIs(t, ExportRedacted[*mockRedactedProfile](profiler, &out, RedactOptions{Format: "toml"}), ErrUnsupportedFormat)
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+type mockINIProfile struct {
+	Name      string   `json:"name"`
+	Region    string   `json:"region"`
+	Output    string   `json:"output"`
+	AccountID string   `json:"account_id" ini:"sso_account_id"`
+	Retries   int      `json:"retries" ini:"max_attempts"`
+	Scopes    []string `json:"scopes"`
+	S3Threads int      `json:"s3_threads"`
+}
+
+func (p *mockINIProfile) GetName() string {
+	return p.Name
+}
+
+func TestImportINI(t *testing.T) {
+	const config = `# ~/.aws/config
+[default]
+region = us-east-1
+
+[profile Dev Account]
+region=eu-west-1
+output = json
+sso_account_id = 123456789012
+max_attempts = 5
+scopes = read, write
+s3 =
+  max_concurrent_requests = 20
+
+[sso-session corp]
+sso_region = us-east-1
+
+[profile existing]
+region = us-west-2
+
+[profile bad]
+max_attempts = many
+`
+	profiler, err := New("test-import-ini", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	require.NoError(t, profiler.AddProfile(&mockINIProfile{Name: "existing"}, false))
+
+	results, err := ImportINI(profiler, bytes.NewReader([]byte(config)), INIImportOptions[*mockINIProfile]{
+		Mapping:    map[string]string{"s3.max_concurrent_requests": "s3_threads"},
+		SetDefault: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	require.Equal(t, ImportResult{Name: "default", ImportedAs: "default", Action: ImportAdded}, results[0])
+	require.Equal(t, ImportResult{Name: "profile Dev Account", ImportedAs: "dev-account", Action: ImportAdded}, results[1])
+	require.Equal(t, ImportSkipped, results[2].Action)
+	require.ErrorIs(t, results[3].Err, ErrProfileNameConflict)
+	require.ErrorIs(t, results[4].Err, ErrUnsupportedFormat)
+
+	dev, err := NewTypedProfiler[*mockINIProfile](profiler).Get("dev-account")
+	require.NoError(t, err)
+	require.Equal(t, &mockINIProfile{
+		Name:      "dev-account",
+		Region:    "eu-west-1",
+		Output:    "json",
+		AccountID: "123456789012",
+		Retries:   5,
+		Scopes:    []string{"read", "write"},
+		S3Threads: 20,
+	}, dev)
+	require.Equal(t, "default", profiler.globalStore.GetDefaultProfile())
+
+	_, err = ImportINI(profiler, bytes.NewReader([]byte("region = us-east-1\n")), INIImportOptions[*mockINIProfile]{})
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	name, err := NormalizeProfileName("  Staging/EU (new)_")
+	require.NoError(t, err)
+	require.Equal(t, "staging-eu-new", name)
+	_, err = NormalizeProfileName("***")
+	require.Error(t, err)
+
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+func TestProfileHistory(t *testing.T) {
+	dir := t.TempDir()
+	profiler, err := New("test-history", WithPlainFileStore(dir), WithProfileHistory(HistoryRetention{MaxRevisions: 2}))
+	require.NoError(t, err)
+	profiles := NewTypedProfiler[*mockProfile](profiler)
+	require.NoError(t, profiles.Add(&mockProfile{Name: "history", TestValue: "v1"}, true))
+
+	history, err := ProfileHistory(profiler, "history")
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	for _, value := range []string{"v2", "v3", "v4"} {
+		profile, err := profiles.Use("history")
+		require.NoError(t, err)
+		profile.TestValue = value
+		require.NoError(t, profiles.Update(profile))
+	}
+
+	// only the last two revisions (v2 and v3) are kept
+	history, err = ProfileHistory(profiler, "history")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, []int{2, 3}, []int{history[0].Revision, history[1].Revision})
+	require.ErrorIs(t, RestoreProfile[*mockProfile](profiler, "history", 1), ErrProfileRevisionNotFound)
+
+	require.NoError(t, RestoreProfile[*mockProfile](profiler, "history", 2))
+	restored, err := profiles.Get("history")
+	require.NoError(t, err)
+	require.Equal(t, "v2", restored.TestValue)
+
+	// the restore is undoable
+	history, err = ProfileHistory(profiler, "history")
+	require.NoError(t, err)
+	require.Equal(t, 4, history[len(history)-1].Revision)
+	require.NoError(t, RestoreProfile[*mockProfile](profiler, "history", 4))
+	restored, err = profiles.Get("history")
+	require.NoError(t, err)
+	require.Equal(t, "v4", restored.TestValue)
+
+	require.ErrorIs(t, RestoreProfile[*mockProfile](profiler, "missing", 1), ErrMissingProfileName)
+
+	// revisions are removed with the profile
+	require.NoError(t, profiler.Cleanup(true))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestSoftDelete(t *testing.T) {
+	dir := t.TempDir()
+	profiler, err := New("test-soft-delete", WithPlainFileStore(dir), WithSoftDelete())
+	require.NoError(t, err)
+	profiles := NewTypedProfiler[*mockProfile](profiler)
+	require.NoError(t, profiles.Add(&mockProfile{Name: "default"}, true))
+	require.NoError(t, profiles.Add(&mockProfile{Name: "old", TestValue: "keep me"}, false))
+	require.NoError(t, profiles.Add(&mockProfile{Name: "older"}, false))
+
+	require.ErrorIs(t, profiles.Delete("default"), ErrCannotDeleteDefaultProfile)
+	require.NoError(t, profiles.Delete("old"))
+	require.NoError(t, profiles.Delete("older"))
+	require.Equal(t, []string{"default"}, ListProfiles(profiler))
+	deleted := ListDeleted(profiler)
+	require.Len(t, deleted, 2)
+	require.Equal(t, "old", deleted[0].Name)
+	require.False(t, deleted[0].DeletedAt.IsZero())
+
+	// the name stays reserved until the profile is restored or purged
+	require.ErrorIs(t, profiles.Add(&mockProfile{Name: "old"}, false), ErrProfileNameConflict)
+	require.NoError(t, RestoreDeletedProfile(profiler, "old"))
+	restored, err := profiles.Get("old")
+	require.NoError(t, err)
+	require.Equal(t, "keep me", restored.TestValue)
+	require.ErrorIs(t, RestoreDeletedProfile(profiler, "old"), ErrMissingProfileName)
+
+	require.NoError(t, PurgeTrash(profiler, time.Hour))
+	require.Len(t, ListDeleted(profiler), 1)
+	require.NoError(t, PurgeTrash(profiler, 0))
+	require.Empty(t, ListDeleted(profiler))
+	require.NoError(t, profiles.Add(&mockProfile{Name: "older"}, false))
+
+	// a soft cleanup keeps everything in the trash
+	require.NoError(t, profiler.Cleanup(false))
+	require.Empty(t, ListProfiles(profiler))
+	require.Len(t, ListDeleted(profiler), 3)
+	require.NoError(t, RestoreDeletedProfile(profiler, "default"))
+
+	// restoring a profile restores the profiles it inherits from
+	require.NoError(t, profiler.AddProfile(&mockLayeredProfile{Name: "base", Endpoint: "https://base.example.com"}, false))
+	require.NoError(t, profiler.AddProfile(&mockLayeredProfile{Name: "child"}, false))
+	require.NoError(t, SetProfileParent(profiler, "child", "base"))
+	require.NoError(t, profiler.Cleanup(false))
+	require.NoError(t, RestoreDeletedProfile(profiler, "child"))
+	require.ElementsMatch(t, []string{"base", "child"}, ListProfiles(profiler))
+	child, err := NewTypedProfiler[*mockLayeredProfile](profiler).Get("child")
+	require.NoError(t, err)
+	require.Equal(t, "https://base.example.com", child.Endpoint)
+	info, err := GetProfileInfo(profiler, "child")
+	require.NoError(t, err)
+	require.Equal(t, "base", info.Parent)
+
+	require.NoError(t, profiler.Cleanup(true, WithHardDelete()))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestDiffProfiles(t *testing.T) {
+	profiler, err := New("test-diff", WithPlainFileStore(t.TempDir()), WithProfileHistory(HistoryRetention{MaxRevisions: 5}))
+	require.NoError(t, err)
+	profiles := NewTypedProfiler[*mockRedactedProfile](profiler)
+
+	staging := &mockRedactedProfile{Name: "staging", Endpoint: "https://staging.example.com", Token: "a", Port: 443}
+	staging.Auth.User = "admin"
+	prod := &mockRedactedProfile{Name: "prod", Endpoint: "https://prod.example.com", Token: "b", Port: 443}
+	prod.Auth.User = "admin"
+	prod.Auth.Password = "hunter2"
+	require.NoError(t, profiles.Add(staging, true))
+	require.NoError(t, profiles.Add(prod, false))
+
+	diffs, err := DiffProfiles[*mockRedactedProfile](profiler, "staging", "prod")
+	require.NoError(t, err)
+	require.Equal(t, []FieldDiff{
+		{Path: "auth.password", Kind: DiffAdded, New: DefaultRedactPlaceholder},
+		{Path: "endpoint", Kind: DiffChanged, Old: "https://staging.example.com", New: "https://prod.example.com"},
+		{Path: "name", Kind: DiffChanged, Old: "staging", New: "prod"},
+		{Path: "token", Kind: DiffChanged, Old: DefaultRedactPlaceholder, New: DefaultRedactPlaceholder},
+	}, diffs)
+
+	// revisions of one profile
+	current, err := profiles.Use("staging")
+	require.NoError(t, err)
+	current.Port = 8443
+	require.NoError(t, profiles.Update(current))
+	diffs, err = DiffProfileRevisions[*mockRedactedProfile](profiler, "staging", 1, CurrentRevision)
+	require.NoError(t, err)
+	require.Equal(t, []FieldDiff{{Path: "port", Kind: DiffChanged, Old: json.Number("443"), New: json.Number("8443")}}, diffs)
+
+	_, err = DiffProfileRevisions[*mockRedactedProfile](profiler, "staging", 2, CurrentRevision)
+	require.ErrorIs(t, err, ErrProfileRevisionNotFound)
+	_, err = DiffProfiles[*mockRedactedProfile](profiler, "staging", "missing")
+	require.ErrorIs(t, err, ErrMissingProfileName)
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+// nextEvents receives n events from a Watch channel, failing the test if they do not arrive in time
+func nextEvents(t *testing.T, events <-chan Event, n int) []Event {
+	t.Helper()
+	var received []Event
+	timeout := time.After(5 * time.Second)
+	for len(received) < n {
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "watch channel closed")
+			require.NoError(t, event.Err)
+			received = append(received, event)
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for watch events", "received %v", received)
+		}
+	}
+	return received
+}
+
+func TestWatch(t *testing.T) {
+	for name, opts := range map[string]WatchOptions{
+		"file events": {Debounce: 20 * time.Millisecond},
+		"polling":     {Debounce: 20 * time.Millisecond, PollInterval: 20 * time.Millisecond, Poll: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			daemon, err := New("test-watch", WithPlainFileStore(dir), WithWatchOptions(opts))
+			require.NoError(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			events := Watch(ctx, daemon)
+
+			// another process changes the profiles
+			cli, err := New("test-watch", WithPlainFileStore(dir))
+			require.NoError(t, err)
+			profiles := NewTypedProfiler[*mockProfile](cli)
+			require.NoError(t, profiles.Add(&mockProfile{Name: "first"}, true))
+			require.Equal(t, []Event{
+				{Type: EventProfileAdded, Profile: "first"},
+				{Type: EventDefaultProfileChanged, Profile: "first"},
+			}, nextEvents(t, events, 2))
+
+			require.NoError(t, profiles.Add(&mockProfile{Name: "second"}, false))
+			require.Equal(t, []Event{{Type: EventProfileAdded, Profile: "second"}}, nextEvents(t, events, 1))
+
+			second, err := profiles.Use("second")
+			require.NoError(t, err)
+			second.TestValue = "changed"
+			require.NoError(t, profiles.Update(second))
+			require.Equal(t, []Event{{Type: EventProfileUpdated, Profile: "second"}}, nextEvents(t, events, 1))
+
+			require.NoError(t, profiles.SetDefault("second"))
+			require.Equal(t, []Event{{Type: EventDefaultProfileChanged, Profile: "second"}}, nextEvents(t, events, 1))
+
+			require.NoError(t, profiles.Delete("first"))
+			require.Equal(t, []Event{{Type: EventProfileDeleted, Profile: "first"}}, nextEvents(t, events, 1))
+
+			cancel()
+			for range events {
+			}
+			require.NoError(t, cli.Cleanup(true))
+		})
+	}
+}
+
+func TestWatch_FileStore(t *testing.T) {
+	dir := t.TempDir()
+	opts := []profileConfigVariadicFunc{WithFileStore(dir), WithFileStorePassphrase("correct horse battery staple")}
+	daemon, err := New("test-watch-file", append(opts, WithWatchOptions(WatchOptions{Debounce: 20 * time.Millisecond}))...)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := Watch(ctx, daemon)
+
+	cli, err := New("test-watch-file", opts...)
+	require.NoError(t, err)
+	require.NoError(t, cli.AddProfile(&mockProfile{Name: "first"}, true))
+	require.Equal(t, []Event{
+		{Type: EventProfileAdded, Profile: "first"},
+		{Type: EventDefaultProfileChanged, Profile: "first"},
+	}, nextEvents(t, events, 2))
+
+	// only the stored values of the namespace trigger a snapshot
+	w := &watcher{p: daemon}
+	urn := store.BuildNamespaceURN("test-watch-file", "v1")
+	require.True(t, w.isStoreFile(filepath.Join(dir, urn+"."+getStoreKey("first")+".enc")))
+	require.True(t, w.isStoreFile(filepath.Join(dir, urn+"."+global.STORE_KEY_GLOBAL+".json")))
+	require.False(t, w.isStoreFile(filepath.Join(dir, urn+"."+getStoreKey("first")+".nfo")))
+	require.False(t, w.isStoreFile(filepath.Join(dir, ".tmp_profile_rw_test123")))
+	require.False(t, w.isStoreFile(filepath.Join(dir, urn+"."+getStoreKey("first")+".json.tmp123")))
+	require.False(t, w.isStoreFile(filepath.Join(dir, store.BuildNamespaceURN("other", "v1")+".global.enc")))
+}
+
+func TestWatch_KeyringSecrets(t *testing.T) {
+	const configName = "test-watch-secrets"
+	// secrets are kept apart like WithKeyringSecrets, in a store that raises no file events either
+	newSecretStore := store.NewSharedMemoryStore()
+	withSecretStore := func(c profileConfig) profileConfig {
+		c.secretStore = newSecretStore
+		return c
+	}
+	opts := []profileConfigVariadicFunc{WithPlainFileStore(t.TempDir()), withSecretStore}
+	daemon, err := New(configName, append(opts, WithWatchOptions(WatchOptions{Debounce: 20 * time.Millisecond, PollInterval: 20 * time.Millisecond}))...)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := Watch(ctx, daemon)
+
+	cli, err := New(configName, opts...)
+	require.NoError(t, err)
+	require.NoError(t, cli.AddProfile(&mockSecretProfile{Name: "first", Token: "t0ken"}, true))
+	require.Equal(t, []Event{
+		{Type: EventProfileAdded, Profile: "first"},
+		{Type: EventDefaultProfileChanged, Profile: "first"},
+	}, nextEvents(t, events, 2))
+
+	// a secret changed alone raises no file event
+	secrets, err := newSecretStore(configName, getStoreKey("first"))
+	require.NoError(t, err)
+	require.NoError(t, secrets.Set(map[string]string{"token": "rotated"}))
+	require.Equal(t, []Event{{Type: EventProfileUpdated, Profile: "first"}}, nextEvents(t, events, 1))
+
+	require.NoError(t, cli.Cleanup(true))
+}
+
+type mockHookedProfile struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Saves    int    `json:"saves"`
+	loaded   bool
+}
+
+func (p *mockHookedProfile) GetName() string {
+	return p.Name
+}
+
+func (p *mockHookedProfile) Validate() error {
+	if p.Endpoint == "" {
+		return errors.New("endpoint is required")
+	}
+	return nil
+}
+
+func (p *mockHookedProfile) BeforeSave() {
+	p.Saves++
+}
+
+func (p *mockHookedProfile) AfterLoad() {
+	p.loaded = true
+}
+
+func TestHooks(t *testing.T) {
+	var calls []string
+	profiler, err := New("test-hooks", WithPlainFileStore(t.TempDir()), WithHooks(
+		Hooks{
+			Before: func(e HookEvent) error {
+				calls = append(calls, "before "+e.Op+" "+e.Profile)
+				return nil
+			},
+			After: func(e HookEvent) error {
+				calls = append(calls, "after "+e.Op+" "+e.Profile)
+				return nil
+			},
+		},
+		Hooks{
+			Ops: []string{OpDeleteProfile},
+			Before: func(e HookEvent) error {
+				if e.Profile == "protected" {
+					return errors.New("protected profile")
+				}
+				return nil
+			},
+		},
+	))
+	require.NoError(t, err)
+	profiles := NewTypedProfiler[*mockHookedProfile](profiler)
+
+	require.ErrorIs(t, profiles.Add(&mockHookedProfile{Name: "invalid"}, false), ErrProfileInvalid)
+	require.NoError(t, profiles.Add(&mockHookedProfile{Name: "default", Endpoint: "https://example.com"}, true))
+	require.NoError(t, profiles.Add(&mockHookedProfile{Name: "protected", Endpoint: "https://example.com"}, false))
+
+	used, err := profiles.Use("default")
+	require.NoError(t, err)
+	require.True(t, used.loaded)
+	require.Equal(t, 1, used.Saves)
+	require.NoError(t, profiles.Update(used))
+	require.NoError(t, profiles.SetDefault("default"))
+
+	err = profiles.Delete("protected")
+	require.ErrorIs(t, err, ErrOperationVetoed)
+	require.Contains(t, ListProfiles(profiler), "protected")
+
+	require.Equal(t, []string{
+		"before add invalid",
+		"before add default", "after add default",
+		"before add protected", "after add protected",
+		"before use default", "after use default",
+		"before update default", "after update default",
+		"before set-default default", "after set-default default",
+		"before delete protected",
+	}, calls)
+
+	stored, err := profiles.Get("default")
+	require.NoError(t, err)
+	require.Equal(t, 2, stored.Saves)
+
+	// imported profiles are validated like saved ones
+	source, err := New("test-hooks-source", WithPlainFileStore(t.TempDir()))
+	require.NoError(t, err)
+	require.NoError(t, source.AddProfile(&mockProfile{Name: "imported"}, true))
+	var bundle bytes.Buffer
+	require.NoError(t, Export(source, &bundle, ExportOptions{}))
+	_, err = Import[*mockHookedProfile](profiler, &bundle, ImportOptions{})
+	require.ErrorIs(t, err, ErrProfileInvalid)
+	require.NotContains(t, ListProfiles(profiler), "imported")
+	require.NoError(t, profiler.Cleanup(true))
+}
+
+type mockSchemaProfile struct {
+	Name    string            `json:"name"`
+	Port    int               `json:"port"`
+	Tags    []string          `json:"tags,omitempty"`
+	Token   *string           `json:"token,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (p *mockSchemaProfile) GetName() string {
+	return p.Name
+}
+
+func TestProfileSchema(t *testing.T) {
+	var generated map[string]any
+	require.NoError(t, json.Unmarshal(GenerateSchema[*mockSchemaProfile](), &generated))
+	require.Equal(t, "object", generated["type"])
+	require.ElementsMatch(t, []any{"name", "port"}, generated["required"])
+	properties := generated["properties"].(map[string]any)
+	require.Equal(t, map[string]any{"type": "integer"}, properties["port"])
+	require.Equal(t, []any{"string", "null"}, properties["token"].(map[string]any)["type"])
+
+	_, err := New("test-schema", WithInMemoryStore(), WithSchema([]byte(`{"type": 1}`)))
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	schema := []byte(`{"type": "object", "required": ["name", "port"], "properties": {"port": {"type": "integer", "minimum": 1}}}`)
+	profiler, err := New("test-schema", WithPlainFileStore(dir), WithSchema(schema))
+	require.NoError(t, err)
+	require.JSONEq(t, string(schema), string(profiler.Schema()))
+	profiles := NewTypedProfiler[*mockSchemaProfile](profiler)
+
+	require.NoError(t, profiles.Add(&mockSchemaProfile{Name: "valid", Port: 443}, true))
+	require.ErrorIs(t, profiles.Add(&mockSchemaProfile{Name: "invalid"}, false), ErrProfileSchemaViolation)
+
+	// Profiles written without the schema are checked when loaded
+	unchecked, err := New("test-schema", WithPlainFileStore(dir))
+	require.NoError(t, err)
+	require.Nil(t, unchecked.Schema())
+	require.NoError(t, NewTypedProfiler[*mockSchemaProfile](unchecked).Add(&mockSchemaProfile{Name: "edited"}, false))
+
+	profiler, err = New("test-schema", WithPlainFileStore(dir), WithGeneratedSchema[*mockSchemaProfile]())
+	require.NoError(t, err)
+	_, err = GetProfile[*mockSchemaProfile](profiler, "edited")
+	require.NoError(t, err)
+
+	profiler, err = New("test-schema", WithPlainFileStore(dir), WithSchema(schema))
+	require.NoError(t, err)
+	_, err = GetProfile[*mockSchemaProfile](profiler, "edited")
+	require.ErrorIs(t, err, ErrProfileSchemaViolation)
+	_, err = GetProfile[*mockSchemaProfile](profiler, "valid")
+	require.NoError(t, err)
+
+	// imports and restores are checked before they are written
+	var bundle bytes.Buffer
+	require.NoError(t, Export(unchecked, &bundle, ExportOptions{Profiles: []string{"edited"}}))
+	target, err := New("test-schema-import", WithPlainFileStore(t.TempDir()), WithSchema(schema))
+	require.NoError(t, err)
+	_, err = Import[*mockSchemaProfile](target, &bundle, ImportOptions{})
+	require.ErrorIs(t, err, ErrProfileSchemaViolation)
+	require.Empty(t, ListProfiles(target))
+
+	historyDir := t.TempDir()
+	unchecked, err = New("test-schema-history", WithPlainFileStore(historyDir), WithProfileHistory(HistoryRetention{MaxRevisions: 5}))
+	require.NoError(t, err)
+	uncheckedProfiles := NewTypedProfiler[*mockSchemaProfile](unchecked)
+	require.NoError(t, uncheckedProfiles.Add(&mockSchemaProfile{Name: "versioned"}, true))
+	versioned, err := uncheckedProfiles.Use("versioned")
+	require.NoError(t, err)
+	versioned.Port = 443
+	require.NoError(t, uncheckedProfiles.Update(versioned))
+
+	profiler, err = New("test-schema-history", WithPlainFileStore(historyDir), WithProfileHistory(HistoryRetention{MaxRevisions: 5}), WithSchema(schema))
+	require.NoError(t, err)
+	require.ErrorIs(t, RestoreProfile[*mockSchemaProfile](profiler, "versioned", 1), ErrProfileSchemaViolation)
+	restored, err := NewTypedProfiler[*mockSchemaProfile](profiler).Get("versioned")
+	require.NoError(t, err)
+	require.Equal(t, 443, restored.Port)
+}
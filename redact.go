@@ -0,0 +1,193 @@
+package profiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportFormat is the output format of a redacted export.
+type ExportFormat string
+
+const (
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatYAML ExportFormat = "yaml"
+)
+
+// DefaultRedactPlaceholder replaces redacted values unless RedactOptions.Placeholder is set.
+const DefaultRedactPlaceholder = "<redacted>"
+
+// RedactOptions configure redacted exports.
+type RedactOptions struct {
+	// Format of the output. Defaults to ExportFormatJSON.
+	Format ExportFormat
+	// Placeholder replaces redacted values. Defaults to DefaultRedactPlaceholder.
+	Placeholder string
+	// Redact, when set, is called with the dot-separated JSON path of each field (e.g. "auth.token")
+	// and redacts the field when it returns true. Fields tagged `osprofiles:"secret"` are always redacted.
+	Redact func(path string) bool
+}
+
+// redactedNamespace is a redacted dump of all profiles of a namespace
+type redactedNamespace struct {
+	Namespace      string                    `json:"namespace" yaml:"namespace"`
+	DefaultProfile string                    `json:"defaultProfile,omitempty" yaml:"defaultProfile,omitempty"`
+	Profiles       map[string]map[string]any `json:"profiles" yaml:"profiles"`
+}
+
+// ExportRedactedProfile writes the specified profile to w with its secret fields replaced by placeholders,
+// e.g. to share its configuration in a support ticket.
+func ExportRedactedProfile[T NamedProfile](p *Profiler, w io.Writer, profileName string, opts RedactOptions) error {
+	profile, err := typedProfile[T](GetProfile[T](p, profileName))
+	if err != nil {
+		return p.newProfileError(OpExportProfiles, profileName, err)
+	}
+	redacted, err := RedactProfile(profile, opts)
+	if err != nil {
+		return p.newProfileError(OpExportProfiles, profileName, err)
+	}
+	return p.newProfileError(OpExportProfiles, profileName, writeExport(w, redacted, opts.Format))
+}
+
+// ExportRedacted writes all profiles of the namespace, keyed by name, to w with their secret fields
+// replaced by placeholders. Profiles that fail to load abort the export.
+func ExportRedacted[T NamedProfile](p *Profiler, w io.Writer, opts RedactOptions) error {
+	dump := redactedNamespace{
+		Namespace:      p.config.configName,
+		DefaultProfile: p.defaultProfile(),
+		Profiles:       make(map[string]map[string]any),
+	}
+	for _, name := range ListProfiles(p) {
+		profile, err := typedProfile[T](GetProfile[T](p, name))
+		if err != nil {
+			return p.newProfileError(OpExportProfiles, name, err)
+		}
+		if dump.Profiles[name], err = RedactProfile(profile, opts); err != nil {
+			return p.newProfileError(OpExportProfiles, name, err)
+		}
+	}
+	return p.newProfileError(OpExportProfiles, "", writeExport(w, dump, opts.Format))
+}
+
+// RedactProfile returns the JSON representation of profile as a map, with the fields tagged
+// `osprofiles:"secret"` at any depth, and those selected by opts.Redact, replaced by a placeholder.
+// Fields that are absent or null are left as they are.
+func RedactProfile(profile NamedProfile, opts RedactOptions) (map[string]any, error) {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	placeholder := opts.Placeholder
+	if placeholder == "" {
+		placeholder = DefaultRedactPlaceholder
+	}
+	return redactValue(fields, reflect.TypeOf(profile), "", opts.Redact, placeholder).(map[string]any), nil
+}
+
+// redactValue redacts a decoded JSON value of Go type t in place, where path is its JSON path
+func redactValue(v any, t reflect.Type, path string, redact func(string) bool, placeholder string) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := joinJSONPath(path, key)
+			childType, secret := jsonFieldType(t, key)
+			if child != nil && (secret || (redact != nil && redact(childPath))) {
+				v[key] = placeholder
+				continue
+			}
+			v[key] = redactValue(child, childType, childPath, redact, placeholder)
+		}
+	case []any:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+		for i, child := range v {
+			v[i] = redactValue(child, elemType, joinJSONPath(path, strconv.Itoa(i)), redact, placeholder)
+		}
+	case json.Number:
+		// Keep integers exact in both JSON and YAML output
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// jsonFieldType returns the Go type of the JSON key of a struct or map of type t, and whether the
+// key is a secret field. Fields of embedded structs are promoted as in encoding/json.
+func jsonFieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	if t == nil {
+		return nil, false
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem(), false
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				embedded := field.Type
+				if embedded.Kind() == reflect.Pointer {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					if ft, secret := jsonFieldType(embedded, key); ft != nil {
+						return ft, secret
+					}
+					continue
+				}
+			}
+			if field.IsExported() && store.JSONFieldName(field) == key {
+				return field.Type, store.IsSecretField(field)
+			}
+		}
+	}
+	return nil, false
+}
+
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// writeExport encodes v to w in the requested format
+func writeExport(w io.Writer, v any, format ExportFormat) error {
+	switch format {
+	case "", ExportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case ExportFormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
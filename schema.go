@@ -0,0 +1,209 @@
+package profiles
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// schemaURL identifies the attached schema to the compiler; it is never fetched
+const schemaURL = "osprofiles:///profile.schema.json"
+
+// WithSchema attaches a JSON Schema that profiles must match. It is enforced when a profile is
+// saved, imported or restored and when it is loaded, after inherited values are merged, and a
+// mismatch is reported as ErrProfileSchemaViolation. New fails if the schema does not compile.
+func WithSchema(schema []byte) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.schema = schema
+		return c
+	}
+}
+
+// WithGeneratedSchema attaches the schema generated from the profile type T, see GenerateSchema
+// and WithSchema.
+//
+// Example:
+//
+//	New("example_app", WithGeneratedSchema[*MyProfile]())
+func WithGeneratedSchema[T NamedProfile]() profileConfigVariadicFunc {
+	return WithSchema(GenerateSchema[T]())
+}
+
+// GenerateSchema returns a JSON Schema for the profile type T, derived from its `json` struct
+// tags. Fields without `omitempty` are required, pointers are nullable and unknown fields are
+// allowed so older profiles keep loading when fields are removed.
+func GenerateSchema[T NamedProfile]() []byte {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema := typeSchema(t, map[reflect.Type]bool{})
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = t.Name()
+
+	data, _ := json.MarshalIndent(schema, "", "  ")
+	return data
+}
+
+// Schema returns the JSON Schema profiles are validated against, or nil when none is attached
+func (p *Profiler) Schema() []byte {
+	return bytes.Clone(p.config.schema)
+}
+
+// compileSchema compiles the schema attached to the configuration, if any
+func compileSchema(schema []byte) (*jsonschema.Schema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(schemaURL, doc); err != nil {
+		return nil, fmt.Errorf("invalid profile schema: %w", err)
+	}
+	compiled, err := c.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile schema: %w", err)
+	}
+	return compiled, nil
+}
+
+// validateSchema checks the JSON data of a profile against a compiled schema, if any
+func validateSchema(schema *jsonschema.Schema, data []byte) error {
+	if schema == nil {
+		return nil
+	}
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrProfileCorrupt, err)
+	}
+	if err := schema.Validate(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrProfileSchemaViolation, err)
+	}
+	return nil
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// typeSchema returns the schema of values of type t, as encoded by encoding/json. Types
+// with custom encodings and recursive references accept any value.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		schema := typeSchema(t.Elem(), seen)
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+		}
+		return schema
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]any{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string
+			return map[string]any{"type": []string{"string", "null"}}
+		}
+		return map[string]any{"type": []string{"array", "null"}, "items": typeSchema(t.Elem(), seen)}
+	case reflect.Array:
+		return map[string]any{
+			"type":     "array",
+			"items":    typeSchema(t.Elem(), seen),
+			"minItems": t.Len(),
+			"maxItems": t.Len(),
+		}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": typeSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]any{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := map[string]any{}
+		required := []string{}
+		structProperties(t, seen, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+// structProperties adds the schemas of the encoded fields of struct type t, followed by those
+// promoted from embedded structs, which its own fields take precedence over
+func structProperties(t reflect.Type, seen map[reflect.Type]bool, properties map[string]any, required *[]string) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embedded = append(embedded, fieldType)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := properties[name]; ok {
+			continue
+		}
+
+		schema := typeSchema(field.Type, seen)
+		if strings.Contains(","+opts+",", ",string,") {
+			schema = map[string]any{"type": "string"}
+		}
+		properties[name] = schema
+		if !strings.Contains(","+opts+",", ",omitempty,") && !strings.Contains(","+opts+",", ",omitzero,") {
+			*required = append(*required, name)
+		}
+	}
+	for _, e := range embedded {
+		structProperties(e, seen, properties, required)
+	}
+}
@@ -0,0 +1,108 @@
+package profiles
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+
+	"github.com/jrschumacher/go-osprofiles/internal/global"
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+// systemProfiles is a read-only layer of profiles managed by administrators, stored as plain JSON
+// files with the same layout as WithPlainFileStore
+type systemProfiles struct {
+	newStore store.NewStoreInterface
+	config   global.GlobalConfig
+}
+
+// WithSystemProfiles layers read-only, administrator-managed profiles from dir under the user's
+// profiles, typically platform.SystemAppConfigDirectory() (e.g. /etc/<publisher>/<app>). The directory
+// uses the WithPlainFileStore layout, so it can be prepared with a Profiler using WithPlainFileStore(dir).
+// User profiles shadow system profiles of the same name. Setting "lockDefaultProfile" in the system
+// global configuration enforces its "defaultProfile" as the user's default profile.
+func WithSystemProfiles(dir string) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.systemProfilesDir = dir
+		return c
+	}
+}
+
+// loadSystemProfiles reads the system layer, which is empty when dir does not exist
+func loadSystemProfiles(configName, dir string) (*systemProfiles, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	s := &systemProfiles{newStore: store.NewPlainFileStoreInDirectory(dir)}
+	globalStore, err := s.newStore(configName, global.STORE_KEY_GLOBAL)
+	if err != nil {
+		return nil, err
+	}
+	if !globalStore.Exists() {
+		return s, nil
+	}
+	data, err := globalStore.Get()
+	if err != nil {
+		return nil, mapStoreError(err)
+	}
+	if err := json.Unmarshal(data, &s.config); err != nil {
+		return nil, errors.Join(ErrProfileCorrupt, err)
+	}
+	return s, nil
+}
+
+// IsSystemProfile returns true if the profile is provided by the read-only system layer
+// and not shadowed by a user profile of the same name.
+func IsSystemProfile(p *Profiler, profileName string) bool {
+	return !p.globalStore.ProfileExists(profileName) && p.systemProfileExists(profileName)
+}
+
+func (p *Profiler) systemProfileExists(profileName string) bool {
+	return p.systemProfiles != nil && slices.Contains(p.systemProfiles.config.Profiles, profileName)
+}
+
+// profileExists checks the user profiles and the system layer
+func (p *Profiler) profileExists(profileName string) bool {
+	return p.globalStore.ProfileExists(profileName) || p.systemProfileExists(profileName)
+}
+
+// lockedDefaultProfile returns the default profile enforced by the system layer, if any
+func (p *Profiler) lockedDefaultProfile() string {
+	if p.systemProfiles == nil || !p.systemProfiles.config.LockDefaultProfile {
+		return ""
+	}
+	return p.systemProfiles.config.DefaultProfile
+}
+
+// defaultProfile returns the locked system default, the user's default or the system default
+func (p *Profiler) defaultProfile() string {
+	if locked := p.lockedDefaultProfile(); locked != "" {
+		return locked
+	}
+	if name := p.globalStore.GetDefaultProfile(); name != "" {
+		return name
+	}
+	if p.systemProfiles != nil {
+		return p.systemProfiles.config.DefaultProfile
+	}
+	return ""
+}
+
+// listSystemProfiles returns the system profiles not shadowed by user profiles
+func (p *Profiler) listSystemProfiles() []string {
+	if p.systemProfiles == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range p.systemProfiles.config.Profiles {
+		if !p.globalStore.ProfileExists(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,119 @@
+package profiles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jrschumacher/go-osprofiles/internal/global"
+)
+
+// DeletedProfile is a soft-deleted profile kept in the trash along with its metadata.
+type DeletedProfile = global.DeletedProfile
+
+type cleanupConfig struct {
+	hardDelete bool
+}
+
+type cleanupVariadicFunc func(cleanupConfig) cleanupConfig
+
+// WithSoftDelete moves deleted profiles to a trash instead of removing them. Their stored values,
+// encryption keys and history are kept until the trash is purged, so they can be restored with
+// RestoreDeletedProfile. A profile in the trash keeps its name reserved.
+func WithSoftDelete() profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.softDelete = true
+		return c
+	}
+}
+
+// WithHardDelete makes Cleanup remove profiles, the trash and the global configuration even when
+// the profiler is configured WithSoftDelete.
+func WithHardDelete() cleanupVariadicFunc {
+	return func(c cleanupConfig) cleanupConfig {
+		c.hardDelete = true
+		return c
+	}
+}
+
+// ListDeleted returns the profiles in the trash, sorted by name
+func ListDeleted(p *Profiler) []DeletedProfile {
+	return p.globalStore.ListTrash()
+}
+
+// RestoreDeletedProfile moves a profile from the trash back to the profiles. The profiles it
+// inherits from are restored with it when they are in the trash too, and the profile it inherited
+// from is forgotten if it was purged.
+func RestoreDeletedProfile(p *Profiler, profileName string) error {
+	deleted, ok := p.globalStore.GetDeletedProfile(profileName)
+	if !ok {
+		return p.newProfileError(OpRestoreProfile, profileName, ErrMissingProfileName)
+	}
+	if p.globalStore.ProfileExists(profileName) {
+		return p.newProfileError(OpRestoreProfile, profileName, ErrProfileNameConflict)
+	}
+	// Restore the parent first, so the profile keeps the values it inherits
+	if parent := deleted.Info.Parent; parent != "" && !p.globalStore.ProfileExists(parent) && p.globalStore.InTrash(parent) {
+		if err := RestoreDeletedProfile(p, parent); err != nil {
+			return p.newProfileError(OpRestoreProfile, profileName, err)
+		}
+	}
+	if err := p.globalStore.RestoreFromTrash(profileName); err != nil {
+		return p.newProfileError(OpRestoreProfile, profileName, err)
+	}
+	if parent := p.globalStore.GetProfileInfo(profileName).Parent; parent != "" && !p.globalStore.ProfileExists(parent) {
+		return p.updateProfileInfo(profileName, func(info *ProfileInfo) {
+			info.Parent = ""
+		})
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes the profiles that have been in the trash for longer than olderThan.
+// A zero olderThan empties the trash.
+func PurgeTrash(p *Profiler, olderThan time.Duration) error {
+	return p.newProfileError(OpPurgeTrash, "", p.purgeTrash(time.Now().UTC().Add(-olderThan)))
+}
+
+// trashProfile moves a profile to the trash, keeping its stored value
+func (p *Profiler) trashProfile(profileName string) error {
+	if err := p.globalStore.TrashProfile(profileName); err != nil {
+		return err
+	}
+	if p.currentProfileStore != nil && p.currentProfileStore.GetProfileName() == profileName {
+		p.currentProfileStore = nil
+	}
+	return nil
+}
+
+// purgeTrash permanently deletes the profiles moved to the trash before cutoff
+func (p *Profiler) purgeTrash(cutoff time.Time) error {
+	if p.globalStore == nil {
+		return nil
+	}
+	for _, deleted := range p.globalStore.ListTrash() {
+		if deleted.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := p.purgeDeletedProfile(deleted); err != nil {
+			return p.newProfileError(OpPurgeTrash, deleted.Name, err)
+		}
+	}
+	return nil
+}
+
+// purgeDeletedProfile removes the stored value and revisions of a profile in the trash
+func (p *Profiler) purgeDeletedProfile(deleted DeletedProfile) error {
+	s, err := newStoreFactory(p.config)(p.config.configName, getStoreKey(deleted.Name))
+	if err != nil {
+		return err
+	}
+	if s.Exists() {
+		if err := s.Delete(); err != nil {
+			return fmt.Errorf("%w %q: %w", ErrDeletingProfile, deleted.Name, mapStoreError(err))
+		}
+	}
+	if err := p.deleteRevisions(deleted.Name, deleted.Info.History); err != nil {
+		return err
+	}
+	return p.globalStore.RemoveFromTrash(deleted.Name)
+}
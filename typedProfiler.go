@@ -0,0 +1,100 @@
+package profiles
+
+import (
+	"fmt"
+	"iter"
+)
+
+// TypedProfiler wraps a Profiler for a single profile type so profiles are returned as T
+// without repeating the type parameter or asserting the type at each call site.
+//
+// Example:
+//
+//	profiler, err := New("example_app")
+//	profiles := NewTypedProfiler[*MyProfile](profiler)
+//	current, err := profiles.UseDefault()
+type TypedProfiler[T NamedProfile] struct {
+	profiler *Profiler
+}
+
+// NewTypedProfiler returns a TypedProfiler for profiles of type T managed by p.
+func NewTypedProfiler[T NamedProfile](p *Profiler) *TypedProfiler[T] {
+	return &TypedProfiler[T]{profiler: p}
+}
+
+// Profiler returns the underlying Profiler.
+func (t *TypedProfiler[T]) Profiler() *Profiler {
+	return t.profiler
+}
+
+// Add adds a new profile, optionally setting it as the default.
+func (t *TypedProfiler[T]) Add(profile T, setDefault bool) error {
+	return t.profiler.AddProfile(profile, setDefault)
+}
+
+// Get returns the stored profile with the specified name.
+func (t *TypedProfiler[T]) Get(profileName string) (T, error) {
+	return typedProfile[T](GetProfile[T](t.profiler, profileName))
+}
+
+// Use sets the current profile to the specified profile name and returns it.
+func (t *TypedProfiler[T]) Use(profileName string) (T, error) {
+	return typedProfile[T](UseProfile[T](t.profiler, profileName))
+}
+
+// UseDefault sets the current profile to the default profile and returns it.
+func (t *TypedProfiler[T]) UseDefault() (T, error) {
+	return typedProfile[T](UseDefaultProfile[T](t.profiler))
+}
+
+// Current returns the current profile.
+func (t *TypedProfiler[T]) Current() (T, error) {
+	return typedProfile[T](GetCurrentProfile(t.profiler))
+}
+
+// Update replaces the current profile with the provided profile and saves it.
+func (t *TypedProfiler[T]) Update(profile T) error {
+	return UpdateCurrentProfile(t.profiler, profile)
+}
+
+// SetDefault sets the specified profile as the default profile.
+func (t *TypedProfiler[T]) SetDefault(profileName string) error {
+	return SetDefaultProfile(t.profiler, profileName)
+}
+
+// Delete removes the specified profile from storage.
+func (t *TypedProfiler[T]) Delete(profileName string) error {
+	return DeleteProfile[T](t.profiler, profileName)
+}
+
+// List returns all stored profiles in the order they were added.
+func (t *TypedProfiler[T]) List() ([]T, error) {
+	names := ListProfiles(t.profiler)
+	profiles := make([]T, 0, len(names))
+	for _, name := range names {
+		profile, err := t.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// All iterates over all stored profiles that load successfully. See AllProfiles.
+func (t *TypedProfiler[T]) All() iter.Seq2[string, T] {
+	return AllProfiles[T](t.profiler)
+}
+
+// typedProfile returns the profile held by the store as T
+func typedProfile[T NamedProfile](store *ProfileStore, err error) (T, error) {
+	var zero T
+	if err != nil {
+		return zero, err
+	}
+	profile, ok := store.Profile.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: stored %T, requested %T", ErrProfileTypeMismatch, store.Profile, zero)
+	}
+	return profile, nil
+}
@@ -0,0 +1,318 @@
+package profiles
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jrschumacher/go-osprofiles/internal/global"
+	"github.com/jrschumacher/go-osprofiles/pkg/store"
+)
+
+const (
+	defaultWatchPollInterval = 2 * time.Second
+	defaultWatchDebounce     = 100 * time.Millisecond
+)
+
+// EventType is the kind of change reported by Watch.
+type EventType string
+
+const (
+	EventProfileAdded          EventType = "added"
+	EventProfileUpdated        EventType = "updated"
+	EventProfileDeleted        EventType = "deleted"
+	EventDefaultProfileChanged EventType = "default-changed"
+	// EventError reports a failure to read the stored profiles. Watching continues.
+	EventError EventType = "error"
+)
+
+// Event is a change to the stored profiles reported by Watch.
+type Event struct {
+	Type EventType
+	// Profile is the name of the changed profile, or the new default profile for
+	// EventDefaultProfileChanged (empty when the default was unset).
+	Profile string
+	// Err is set for EventError.
+	Err error
+}
+
+// WatchOptions configure how Watch detects changes.
+type WatchOptions struct {
+	// PollInterval is the time between checks of stores that are not file-backed and of secrets
+	// kept apart by WithKeyringSecrets. Defaults to 2s.
+	PollInterval time.Duration
+	// Debounce is the time changes must settle before they are reported. Defaults to 100ms.
+	Debounce time.Duration
+	// Poll checks at PollInterval even when the store is file-backed and could be watched.
+	Poll bool
+}
+
+// WithWatchOptions configures the change detection of Watch.
+func WithWatchOptions(opts WatchOptions) profileConfigVariadicFunc {
+	return func(c profileConfig) profileConfig {
+		c.watch = opts
+		return c
+	}
+}
+
+// watchSnapshot is the state of the stored profiles compared by Watch
+type watchSnapshot struct {
+	profiles       []string
+	defaultProfile string
+	fingerprints   map[string][sha256.Size]byte
+}
+
+// watcher reports the changes to the stored profiles of a profiler
+type watcher struct {
+	p        *Profiler
+	opts     WatchOptions
+	newStore store.NewStoreInterface
+	events   chan Event
+	snapshot watchSnapshot
+
+	// globalStore and profileStores are constructed once and reused by every snapshot, as
+	// constructing file-backed stores touches the watched directory
+	globalStore   store.StoreInterface
+	profileStores map[string]store.StoreInterface
+}
+
+// Watch reports changes to the stored profiles and the default profile, including those made by
+// other processes, until ctx is done, when the channel is closed. File-backed stores are watched
+// for file changes, while other drivers and the secrets kept by WithKeyringSecrets are polled, see
+// WithWatchOptions. Changes are read from the
+// store, so the profiler's own view (e.g. ListProfiles) is not refreshed; create a new Profiler to
+// reload it.
+//
+// Example:
+//
+//	for event := range Watch(ctx, profiler) {
+//		if event.Type == EventDefaultProfileChanged {
+//			fmt.Println("default profile is now", event.Profile)
+//		}
+//	}
+func Watch(ctx context.Context, p *Profiler) <-chan Event {
+	opts := p.config.watch
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultWatchPollInterval
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = defaultWatchDebounce
+	}
+
+	w := &watcher{
+		p:             p,
+		opts:          opts,
+		newStore:      newStoreFactory(p.config),
+		events:        make(chan Event),
+		profileStores: make(map[string]store.StoreInterface),
+	}
+	w.globalStore, _ = w.newStore(p.config.configName, global.STORE_KEY_GLOBAL)
+	// Start watching before the initial snapshot so no change is missed
+	changes := w.watchChanges(ctx)
+	w.snapshot, _ = w.readSnapshot()
+	go w.run(ctx, changes)
+	return w.events
+}
+
+func (w *watcher) run(ctx context.Context, changes <-chan struct{}) {
+	defer close(w.events)
+
+	debounce := time.NewTimer(w.opts.Debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changes:
+			debounce.Reset(w.opts.Debounce)
+		case <-debounce.C:
+			if !w.emitChanges(ctx) {
+				return
+			}
+		}
+	}
+}
+
+// watchChanges signals possible changes, from file events when the global configuration is
+// file-backed and otherwise at every poll interval. Secrets kept apart by WithKeyringSecrets raise
+// no file events, so they are polled as well.
+func (w *watcher) watchChanges(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	watched := false
+	if dir := w.watchDirectory(); dir != "" && !w.opts.Poll {
+		if fsw, err := fsnotify.NewWatcher(); err == nil {
+			if err := fsw.Add(dir); err == nil {
+				watched = true
+				go func() {
+					defer fsw.Close()
+					for {
+						select {
+						case <-ctx.Done():
+							return
+						case event, ok := <-fsw.Events:
+							if !ok {
+								return
+							}
+							if w.isStoreFile(event.Name) {
+								notify()
+							}
+						case _, ok := <-fsw.Errors:
+							if !ok {
+								return
+							}
+							notify()
+						}
+					}
+				}()
+			} else {
+				fsw.Close()
+			}
+		}
+	}
+	if watched && w.p.config.secretStore == nil {
+		return changes
+	}
+
+	go func() {
+		ticker := time.NewTicker(w.opts.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				notify()
+			}
+		}
+	}()
+	return changes
+}
+
+// watchDirectory returns the directory holding the global configuration, if it is file-backed
+func (w *watcher) watchDirectory() string {
+	if fileBacked, ok := w.globalStore.(store.FileBackedStore); ok && fileBacked.FilePath() != "" {
+		return filepath.Dir(fileBacked.FilePath())
+	}
+	return ""
+}
+
+// isStoreFile returns true for the files of the profiler's namespace holding stored values, so
+// temporary files and the files of other namespaces sharing the directory are ignored
+func (w *watcher) isStoreFile(path string) bool {
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, store.BuildNamespaceURN(w.p.config.configName, "")) {
+		return false
+	}
+	ext := filepath.Ext(name)
+	return ext == ".enc" || ext == ".json"
+}
+
+// emitChanges sends the events between the last and the current snapshot. It returns false
+// when ctx is done.
+func (w *watcher) emitChanges(ctx context.Context) bool {
+	current, err := w.readSnapshot()
+	var events []Event
+	if err != nil {
+		events = []Event{{Type: EventError, Err: err}}
+	} else {
+		events = w.snapshot.diff(current)
+		w.snapshot = current
+	}
+	for _, event := range events {
+		select {
+		case <-ctx.Done():
+			return false
+		case w.events <- event:
+		}
+	}
+	return true
+}
+
+// readSnapshot reads the global configuration and fingerprints the stored profiles
+func (w *watcher) readSnapshot() (watchSnapshot, error) {
+	snapshot := watchSnapshot{fingerprints: make(map[string][sha256.Size]byte)}
+	if w.globalStore == nil {
+		s, err := w.newStore(w.p.config.configName, global.STORE_KEY_GLOBAL)
+		if err != nil {
+			return snapshot, err
+		}
+		w.globalStore = s
+	}
+	s := w.globalStore
+	if !s.Exists() {
+		return snapshot, nil
+	}
+	data, err := s.Get()
+	if err != nil {
+		return snapshot, mapStoreError(err)
+	}
+	var config global.GlobalConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return snapshot, err
+	}
+
+	snapshot.profiles = config.Profiles
+	snapshot.defaultProfile = config.DefaultProfile
+	for _, name := range config.Profiles {
+		ps, err := w.profileStore(name)
+		if err != nil {
+			return snapshot, err
+		}
+		data, err := ps.Get()
+		if err != nil {
+			return snapshot, w.p.newProfileError(OpGetProfile, name, mapStoreError(err))
+		}
+		snapshot.fingerprints[name] = sha256.Sum256(data)
+	}
+	return snapshot, nil
+}
+
+// profileStore returns the store of a profile, constructing it the first time it is seen
+func (w *watcher) profileStore(profileName string) (store.StoreInterface, error) {
+	if s, ok := w.profileStores[profileName]; ok {
+		return s, nil
+	}
+	s, err := w.newStore(w.p.config.configName, getStoreKey(profileName))
+	if err != nil {
+		return nil, err
+	}
+	w.profileStores[profileName] = s
+	return s, nil
+}
+
+// diff returns the events that turn s into next, with deletions first and a change of the
+// default profile last
+func (s watchSnapshot) diff(next watchSnapshot) []Event {
+	var events []Event
+	for _, name := range s.profiles {
+		if !slices.Contains(next.profiles, name) {
+			events = append(events, Event{Type: EventProfileDeleted, Profile: name})
+		}
+	}
+	for _, name := range next.profiles {
+		fingerprint, ok := s.fingerprints[name]
+		switch {
+		case !slices.Contains(s.profiles, name):
+			events = append(events, Event{Type: EventProfileAdded, Profile: name})
+		case !ok || fingerprint != next.fingerprints[name]:
+			events = append(events, Event{Type: EventProfileUpdated, Profile: name})
+		}
+	}
+	if s.defaultProfile != next.defaultProfile {
+		events = append(events, Event{Type: EventDefaultProfileChanged, Profile: next.defaultProfile})
+	}
+	return events
+}